@@ -0,0 +1,338 @@
+// Package audit checks lists of password hashes, such as
+// those exported from a user database, against a pwned
+// password list.
+//
+// Hashes are grouped by prefix so that each prefix is only
+// queried once, however many accounts share it. Only the
+// prefixes are sent to the pwned.Source, and the reports
+// identify accounts by user name and line number, never
+// by hash.
+package audit
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"go.tmthrgd.dev/pwned"
+)
+
+// Algorithm is the hash algorithm of an Account's Digest.
+type Algorithm int
+
+// The hash algorithms that can be audited.
+const (
+	SHA1 Algorithm = iota
+	NTLM
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case SHA1:
+		return "SHA1"
+	case NTLM:
+		return "NTLM"
+	default:
+		return "Algorithm(" + strconv.Itoa(int(a)) + ")"
+	}
+}
+
+// Account is a single account from a hash list.
+type Account struct {
+	// User is the account's user name. It is empty for
+	// lists of bare hashes.
+	User string
+	// Line is the line of the list the account was read
+	// from, starting at one.
+	Line int
+	// Algorithm is the hash algorithm of Digest.
+	Algorithm Algorithm
+	// Digest is the digest of the account's password. NTLM
+	// digests are padded with zeros.
+	Digest [sha1.Size]byte
+}
+
+// String returns a description of the account that omits
+// the digest, so that accounts can be logged safely.
+func (a Account) String() string {
+	return fmt.Sprintf("%s account %q on line %d", a.Algorithm, a.User, a.Line)
+}
+
+// GoString is like String. It prevents the digest being
+// printed with the %#v verb.
+func (a Account) GoString() string {
+	return a.String()
+}
+
+// Progress reports how far an audit has got.
+type Progress struct {
+	// Prefixes is the number of distinct prefixes, of each
+	// Algorithm, to be queried and Done the number that
+	// have been.
+	Prefixes, Done int
+}
+
+// DefaultConcurrency is the default number of prefixes
+// that are queried concurrently.
+const DefaultConcurrency = 8
+
+// Auditor checks accounts against a pwned.Source.
+type Auditor struct {
+	srcs map[Algorithm]pwned.Source
+
+	concurrency int
+	progress    func(Progress)
+}
+
+// New returns an Auditor that queries src for SHA1
+// digests. Sources for other algorithms can be added with
+// WithSource.
+func New(src pwned.Source, opts ...Option) *Auditor {
+	a := &Auditor{
+		srcs: map[Algorithm]pwned.Source{SHA1: src},
+
+		concurrency: DefaultConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Audit checks each account and returns a Report of those
+// whose password has been pwned, and of the Clusters of
+// accounts that share a password.
+//
+// Each account is checked against the Source for its
+// Algorithm. It is an error if there is no such Source.
+func (a *Auditor) Audit(ctx context.Context, accounts []Account) (*Report, error) {
+	groups := make(map[group][]int)
+	for i := range accounts {
+		if a.srcs[accounts[i].Algorithm] == nil {
+			return nil, fmt.Errorf("pwned/audit: no source for %s digests", accounts[i].Algorithm)
+		}
+
+		prefix, _ := pwned.SplitDigest(accounts[i].Digest)
+		g := group{accounts[i].Algorithm, prefix}
+		groups[g] = append(groups[g], i)
+	}
+
+	keys := make([]group, 0, len(groups))
+	for g := range groups {
+		keys = append(keys, g)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].alg != keys[j].alg {
+			return keys[i].alg < keys[j].alg
+		}
+
+		return keys[i].prefix < keys[j].prefix
+	})
+
+	counts := make([]int, len(accounts))
+	if err := a.query(ctx, keys, func(g group, res []byte, bits int) {
+		for _, i := range groups[g] {
+			_, suffix := pwned.SplitDigest(accounts[i].Digest)
+
+			if bits != 0 {
+				counts[i] = pwned.SearchTruncatedSet(res, suffix, bits)
+			} else {
+				counts[i] = pwned.SearchSet(res, suffix)
+			}
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	clusters, shared := cluster(accounts, counts)
+
+	rep := &Report{
+		Accounts: len(accounts),
+		Prefixes: len(keys),
+		Clusters: clusters,
+	}
+
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+
+		rep.Findings = append(rep.Findings, Finding{
+			User:   accounts[i].User,
+			Line:   accounts[i].Line,
+			Count:  count,
+			Risk:   pwned.RiskOf(count),
+			Shared: shared[i],
+		})
+	}
+
+	sort.SliceStable(rep.Findings, func(i, j int) bool {
+		return rep.Findings[i].Count > rep.Findings[j].Count
+	})
+
+	return rep, nil
+}
+
+// cluster groups the accounts that share a digest. counts
+// holds the pwned count of each account. shared holds the
+// size of each account's cluster, or zero.
+func cluster(accounts []Account, counts []int) (clusters []Cluster, shared []int) {
+	type key struct {
+		alg    Algorithm
+		digest [sha1.Size]byte
+	}
+
+	groups := make(map[key][]int)
+	for i := range accounts {
+		k := key{accounts[i].Algorithm, accounts[i].Digest}
+		groups[k] = append(groups[k], i)
+	}
+
+	shared = make([]int, len(accounts))
+	for _, idxs := range groups {
+		if len(idxs) < 2 {
+			continue
+		}
+
+		c := Cluster{
+			Size:    len(idxs),
+			Count:   counts[idxs[0]],
+			Risk:    pwned.RiskOf(counts[idxs[0]]),
+			Members: make([]Member, len(idxs)),
+		}
+		for j, i := range idxs {
+			c.Members[j] = Member{accounts[i].User, accounts[i].Line}
+			shared[i] = len(idxs)
+		}
+
+		clusters = append(clusters, c)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		ci, cj := &clusters[i], &clusters[j]
+		switch {
+		case ci.Count != cj.Count:
+			return ci.Count > cj.Count
+		case ci.Size != cj.Size:
+			return ci.Size > cj.Size
+		default:
+			return ci.Members[0].Line < cj.Members[0].Line
+		}
+	})
+
+	return clusters, shared
+}
+
+// group identifies the accounts that share a query.
+type group struct {
+	alg    Algorithm
+	prefix string
+}
+
+// query calls fn with the results of each group. fn is
+// called for each group once and never concurrently.
+func (a *Auditor) query(ctx context.Context, groups []group, fn func(g group, res []byte, bits int)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan group)
+	go func() {
+		defer close(work)
+
+		for _, g := range groups {
+			select {
+			case work <- g:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		progress = Progress{Prefixes: len(groups)}
+	)
+
+	workers := a.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for g := range work {
+				res, bits, err := a.srcs[g.alg].Range(ctx, g.prefix)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+
+					cancel()
+				} else {
+					fn(g, res, bits)
+
+					progress.Done++
+					if a.progress != nil {
+						a.progress(progress)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		// The parent context was cancelled.
+		return ctx.Err()
+	}
+
+	return firstErr
+}
+
+// Option allows the behaviour of the Auditor to be
+// configured.
+type Option func(*Auditor)
+
+// WithConcurrency sets the number of prefixes that are
+// queried concurrently. It defaults to DefaultConcurrency.
+func WithConcurrency(n int) Option {
+	return func(a *Auditor) {
+		a.concurrency = n
+	}
+}
+
+// WithSource sets the pwned.Source that is queried for
+// digests of alg. The source given to New is used for
+// SHA1.
+//
+// For NTLM, src must return results for zero padded NTLM
+// digests, as returned by gateway.New with gateway.WithNTLM.
+func WithSource(alg Algorithm, src pwned.Source) Option {
+	return func(a *Auditor) {
+		a.srcs[alg] = src
+	}
+}
+
+// WithProgress sets a function that is called each time a
+// prefix has been queried. It is never called
+// concurrently.
+func WithProgress(fn func(Progress)) Option {
+	return func(a *Auditor) {
+		a.progress = fn
+	}
+}
@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+func hash(password string) string {
+	digest := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(digest[:]))
+}
+
+type countingSource struct {
+	pwned.Source
+	calls int32
+}
+
+func (s *countingSource) Range(ctx context.Context, prefix string) ([]byte, int, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return s.Source.Range(ctx, prefix)
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	list := "# exported users\n" +
+		"alice:" + hash("password") + "\n" +
+		"bob:" + strings.ToLower(hash("correct horse battery staple")) + "\r\n" +
+		"\n" +
+		"carol:" + hash("password") + "\n" +
+		hash("P@ssw0rd") + "\n" +
+		"dave:x:" + hash("P@ssw0rd") + "\n" +
+		"erin:" + hash("correct horse battery staple") + "\n"
+
+	accounts, err := Read(strings.NewReader(list))
+	require.NoError(t, err)
+	require.Len(t, accounts, 6)
+	assert.Equal(t, "alice", accounts[0].User)
+	assert.Equal(t, 2, accounts[0].Line)
+	assert.Equal(t, "", accounts[3].User)
+	assert.Equal(t, "dave:x", accounts[4].User)
+
+	words := []string{"P@ssw0rd"}
+	for i := 0; i < 20; i++ {
+		words = append(words, "password")
+	}
+
+	src := &countingSource{Source: pwned.RangerSource(wordlist.New(words...))}
+
+	var progress []Progress
+	rep, err := New(src, WithConcurrency(2), WithProgress(func(p Progress) {
+		progress = append(progress, p)
+	})).Audit(context.Background(), accounts)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(3), src.calls)
+	assert.Equal(t, []Progress{{3, 1}, {3, 2}, {3, 3}}, progress)
+
+	assert.Equal(t, &Report{
+		Accounts: 6,
+		Prefixes: 3,
+		Findings: []Finding{
+			{"alice", 2, 16, pwned.RiskMedium, 2},
+			{"carol", 5, 16, pwned.RiskMedium, 2},
+			{"", 6, 1, pwned.RiskLow, 2},
+			{"dave:x", 7, 1, pwned.RiskLow, 2},
+		},
+		Clusters: []Cluster{
+			{2, 16, pwned.RiskMedium, []Member{{"alice", 2}, {"carol", 5}}},
+			{2, 1, pwned.RiskLow, []Member{{"", 6}, {"dave:x", 7}}},
+			{2, 0, pwned.RiskNone, []Member{{"bob", 3}, {"erin", 8}}},
+		},
+	}, rep)
+
+	var buf bytes.Buffer
+	require.NoError(t, rep.WriteCSV(&buf))
+	assert.Equal(t, "user,line,count,risk,shared\nalice,2,16,medium,2\ncarol,5,16,medium,2\n,6,1,low,2\ndave:x,7,1,low,2\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, rep.WriteClustersCSV(&buf))
+	assert.Equal(t, "cluster,size,count,risk,user,line\n"+
+		"1,2,16,medium,alice,2\n1,2,16,medium,carol,5\n"+
+		"2,2,1,low,,6\n2,2,1,low,dave:x,7\n"+
+		"3,2,0,none,bob,3\n3,2,0,none,erin,8\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, rep.WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"risk": "medium"`)
+	assert.NotContains(t, buf.String(), hash("password"))
+}
+
+func TestReadInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Read(strings.NewReader("alice:" + hash("password") + "\nbob:5BAA61E4C9B93F3F0682250B6CF8331B7EE68FDG\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.NotContains(t, err.Error(), "5BAA6")
+}
+
+type failingSource struct{}
+
+func (failingSource) Range(ctx context.Context, prefix string) ([]byte, int, error) {
+	return nil, 0, errors.New("failed")
+}
+
+func TestAuditError(t *testing.T) {
+	t.Parallel()
+
+	accounts, err := Read(strings.NewReader(hash("password") + "\n" + hash("P@ssw0rd") + "\n"))
+	require.NoError(t, err)
+
+	_, err = New(failingSource{}).Audit(context.Background(), accounts)
+	assert.EqualError(t, err, "failed")
+}
+
+// ntlmPassword is the NTLM hash of "password".
+const ntlmPassword = "8846F7EAEE8FB117AD06BDD830B7586C"
+
+// shaPassword is the {SHA} scheme hash of "password".
+const shaPassword = "{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="
+
+type ntlmSource map[string]uint64
+
+func (s ntlmSource) Range(ctx context.Context, prefix string) ([]byte, int, error) {
+	var res []byte
+	for hash, count := range s {
+		var digest [sha1.Size]byte
+		hex.Decode(digest[:], []byte(hash))
+
+		if pfx, suffix := pwned.SplitDigest(digest); pfx == prefix {
+			res = pwned.AppendResult(res, suffix, count)
+		}
+	}
+
+	return res, 0, nil
+}
+
+func TestReadSecretsdump(t *testing.T) {
+	t.Parallel()
+
+	dump := "[*] Dumping Domain Credentials (domain\\uid:rid:lmhash:nthash)\n" +
+		"CORP\\alice:1104:aad3b435b51404eeaad3b435b51404ee:" + strings.ToLower(ntlmPassword) + ":::\n" +
+		"bob:1105:aad3b435b51404eeaad3b435b51404ee:7A21990FCD3D759941E45C490F143D5F::: (status=Enabled)\n" +
+		"CORP\\alice:aes256-cts-hmac-sha1-96:3f2b4c\n" +
+		"[*] Cleaning up...\n"
+
+	accounts, err := ReadSecretsdump(strings.NewReader(dump))
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "CORP\\alice", accounts[0].User)
+	assert.Equal(t, 2, accounts[0].Line)
+	assert.Equal(t, NTLM, accounts[0].Algorithm)
+	assert.Equal(t, ntlmPassword, strings.ToUpper(hex.EncodeToString(accounts[0].Digest[:16])))
+	assert.Equal(t, make([]byte, 4), accounts[0].Digest[16:])
+	assert.Equal(t, "bob", accounts[1].User)
+
+	assert.NotContains(t, fmt.Sprint(accounts[0]), "8846")
+	assert.NotContains(t, fmt.Sprintf("%#v", accounts[0]), "136")
+
+	_, err = ReadSecretsdump(strings.NewReader("bob:1105:aad3b435b51404eeaad3b435b51404ee:" + ntlmPassword[:30] + ":::\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1")
+	assert.NotContains(t, err.Error(), "aad3b")
+}
+
+func TestReadLDIF(t *testing.T) {
+	t.Parallel()
+
+	ldif := "version: 1\n" +
+		"\n" +
+		"# alice, people, example.com\n" +
+		"dn: uid=alice,ou=people,dc=example,dc=com\n" +
+		"objectClass: inetOrgPerson\n" +
+		"userPassword: " + shaPassword[:10] + "\n" +
+		" " + shaPassword[10:] + "\n" +
+		"\n" +
+		"dn: uid=bob,ou=people,dc=example,dc=com\n" +
+		"userPassword:: " + base64.StdEncoding.EncodeToString([]byte(shaPassword)) + "\n" +
+		"\n" +
+		"dn: uid=carol,ou=people,dc=example,dc=com\n" +
+		"userpassword: {SSHA}DkMTwBl+a/3DQTxCYEApdUtNXGgdUac3\n"
+
+	accounts, err := ReadLDIF(strings.NewReader(ldif))
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", accounts[0].User)
+	assert.Equal(t, 6, accounts[0].Line)
+	assert.Equal(t, SHA1, accounts[0].Algorithm)
+	assert.Equal(t, sha1.Sum([]byte("password")), accounts[0].Digest)
+	assert.Equal(t, "uid=bob,ou=people,dc=example,dc=com", accounts[1].User)
+	assert.Equal(t, accounts[0].Digest, accounts[1].Digest)
+
+	_, err = ReadLDIF(strings.NewReader("dn: uid=alice\nuserPassword: {SHA}W6ph5Mm5Pz8Ggi\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.NotContains(t, err.Error(), "W6ph5")
+}
+
+func TestReadHtpasswd(t *testing.T) {
+	t.Parallel()
+
+	htpasswd := "alice:" + shaPassword + "\n" +
+		"bob:$apr1$Kc5OfFrv$ltF9CXm8p5X2bqsfsQK8e1\n" +
+		"carol:$2y$05$c4WoMPo3SXsafkva.HHa6uXQZWr7oboPiC2bT/r7q1BB8I2s0BRqC\n"
+
+	accounts, err := ReadHtpasswd(strings.NewReader(htpasswd))
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "alice", accounts[0].User)
+	assert.Equal(t, sha1.Sum([]byte("password")), accounts[0].Digest)
+
+	_, err = ReadHtpasswd(strings.NewReader("alice\n"))
+	assert.EqualError(t, err, "pwned/audit: invalid htpasswd entry on line 1")
+}
+
+func TestAuditNTLM(t *testing.T) {
+	t.Parallel()
+
+	accounts, err := ReadSecretsdump(strings.NewReader("alice:500:aad3b435b51404eeaad3b435b51404ee:" + ntlmPassword + ":::\n"))
+	require.NoError(t, err)
+
+	sha1Accounts, err := ReadHtpasswd(strings.NewReader("bob:" + shaPassword + "\n"))
+	require.NoError(t, err)
+	accounts = append(accounts, sha1Accounts...)
+
+	words := pwned.RangerSource(wordlist.New("password"))
+
+	_, err = New(words).Audit(context.Background(), accounts)
+	assert.EqualError(t, err, "pwned/audit: no source for NTLM digests")
+
+	rep, err := New(words, WithSource(NTLM, ntlmSource{ntlmPassword: 9545824})).Audit(context.Background(), accounts)
+	require.NoError(t, err)
+	assert.Equal(t, &Report{
+		Accounts: 2,
+		Prefixes: 2,
+		Findings: []Finding{
+			{"alice", 1, 1 << 23, pwned.RiskCritical, 0},
+			{"bob", 1, 1, pwned.RiskLow, 0},
+		},
+	}, rep)
+}
@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ntlmSize is the size of an NTLM digest.
+const ntlmSize = 16
+
+// ReadSecretsdump parses the NTLM hashes from the output of
+// secretsdump or pwdump. Account lines are of the form:
+//  DOMAIN\alice:1104:AAD3B435B51404EEAAD3B435B51404EE:8846F7EAEE8FB117AD06BDD830B7586C:::
+// All other lines, such as status messages and Kerberos
+// keys, are ignored. The accounts have an Algorithm of NTLM.
+//
+// Errors identify the line by number and never include its
+// contents.
+func ReadSecretsdump(r io.Reader) ([]Account, error) {
+	var accounts []Account
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Split(strings.TrimSpace(s.Text()), ":")
+		if len(fields) != 7 || fields[0] == "" {
+			continue
+		}
+
+		if _, err := strconv.ParseUint(fields[1], 10, 32); err != nil {
+			// Not an account line.
+			continue
+		}
+
+		acct := Account{User: fields[0], Line: line, Algorithm: NTLM}
+		if !decodeHex(acct.Digest[:ntlmSize], fields[3]) {
+			return nil, fmt.Errorf("pwned/audit: invalid NTLM hash on line %d", line)
+		}
+
+		accounts = append(accounts, acct)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("pwned/audit: failed to read secretsdump: %v", err)
+	}
+
+	return accounts, nil
+}
+
+// ReadLDIF parses the userPassword attributes of an LDIF
+// export, such as one produced by ldapsearch or slapcat.
+// The user name of each account is the DN of the entry.
+//
+// Only {SHA} passwords are returned. Salted and other
+// schemes cannot be checked and are ignored.
+//
+// Errors identify the line by number and never include its
+// contents.
+func ReadLDIF(r io.Reader) ([]Account, error) {
+	var (
+		accounts []Account
+
+		dn string
+
+		// attr holds the attribute currently being read,
+		// which may be folded over several lines.
+		attr     string
+		attrLine int
+	)
+
+	flush := func() error {
+		text, line := attr, attrLine
+		attr, attrLine = "", 0
+		if text == "" || text[0] == '#' || text == "-" {
+			return nil
+		}
+
+		idx := strings.IndexByte(text, ':')
+		if idx <= 0 {
+			return fmt.Errorf("pwned/audit: invalid LDIF on line %d", line)
+		}
+
+		name, value := text[:idx], text[idx+1:]
+		encoded := strings.HasPrefix(value, ":")
+		if encoded {
+			value = value[1:]
+		}
+		value = strings.TrimLeft(value, " ")
+
+		if encoded {
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("pwned/audit: invalid base64 value on line %d", line)
+			}
+
+			value = string(b)
+		}
+
+		switch {
+		case strings.EqualFold(name, "dn"):
+			dn = value
+		case strings.EqualFold(name, "userPassword"):
+			acct := Account{User: dn, Line: line}
+			switch ok, err := decodeSHAScheme(acct.Digest[:], value); {
+			case err != nil:
+				return fmt.Errorf("pwned/audit: invalid {SHA} password on line %d", line)
+			case ok:
+				accounts = append(accounts, acct)
+			}
+		}
+
+		return nil
+	}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		if strings.HasPrefix(text, " ") && attrLine != 0 {
+			attr += text[1:]
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+
+		if text == "" {
+			// A blank line ends the entry.
+			dn = ""
+			continue
+		}
+
+		attr, attrLine = text, line
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("pwned/audit: failed to read LDIF: %v", err)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// ReadHtpasswd parses an Apache htpasswd file. Lines are
+// of the form:
+//  alice:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=
+// Only {SHA} passwords are returned. Other schemes, such as
+// bcrypt and $apr1$, are salted and are ignored.
+//
+// Errors identify the line by number and never include its
+// contents.
+func ReadHtpasswd(r io.Reader) ([]Account, error) {
+	var accounts []Account
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		idx := strings.IndexByte(text, ':')
+		if idx < 0 {
+			return nil, fmt.Errorf("pwned/audit: invalid htpasswd entry on line %d", line)
+		}
+
+		acct := Account{User: text[:idx], Line: line}
+		switch ok, err := decodeSHAScheme(acct.Digest[:], text[idx+1:]); {
+		case err != nil:
+			return nil, fmt.Errorf("pwned/audit: invalid {SHA} password on line %d", line)
+		case ok:
+			accounts = append(accounts, acct)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("pwned/audit: failed to read htpasswd: %v", err)
+	}
+
+	return accounts, nil
+}
+
+// decodeSHAScheme decodes a {SHA} password, as used by LDAP
+// and htpasswd, into dst. ok is false if the password uses
+// a different scheme.
+func decodeSHAScheme(dst []byte, password string) (ok bool, err error) {
+	const scheme = "{SHA}"
+	if len(password) < len(scheme) || !strings.EqualFold(password[:len(scheme)], scheme) {
+		return false, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(password[len(scheme):])
+	if err != nil {
+		return false, err
+	}
+
+	if len(b) != sha1.Size {
+		return false, fmt.Errorf("pwned/audit: invalid digest length %d", len(b))
+	}
+
+	copy(dst, b)
+	return true, nil
+}
+
+func decodeHex(dst []byte, hash string) bool {
+	if len(hash) != hex.EncodedLen(len(dst)) {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(hash))
+	return err == nil
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Read parses a hash list from r. Each line is either a
+// hexadecimal SHA1 digest or a user name and digest
+// separated by a ':', as in:
+//  alice:5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+//  7C4A8D09CA3762AF61E59520943DC26494F8941B
+// The user name may itself contain ':'. Blank lines and
+// lines starting with '#' are ignored.
+//
+// Errors identify the line by number and never include its
+// contents.
+func Read(r io.Reader) ([]Account, error) {
+	var accounts []Account
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		user, hash := "", text
+		if idx := strings.LastIndexByte(text, ':'); idx >= 0 {
+			user, hash = text[:idx], text[idx+1:]
+		}
+
+		acct := Account{User: user, Line: line}
+		if !decodeHex(acct.Digest[:], hash) {
+			return nil, fmt.Errorf("pwned/audit: invalid SHA1 digest on line %d", line)
+		}
+
+		accounts = append(accounts, acct)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("pwned/audit: failed to read hash list: %v", err)
+	}
+
+	return accounts, nil
+}
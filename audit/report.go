@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"go.tmthrgd.dev/pwned"
+)
+
+// Finding is an account whose password has been pwned.
+type Finding struct {
+	User string `json:"user,omitempty"`
+	Line int    `json:"line"`
+	// Count is rounded down to a power of two.
+	Count int        `json:"count"`
+	Risk  pwned.Risk `json:"risk"`
+	// Shared is the size of the Cluster the account
+	// belongs to, or zero if its password is not shared.
+	Shared int `json:"shared,omitempty"`
+}
+
+// Member identifies an account in a Cluster.
+type Member struct {
+	User string `json:"user,omitempty"`
+	Line int    `json:"line"`
+}
+
+// Cluster is a group of accounts that share the same
+// password hash. As the hashes are unsalted, a Cluster is a
+// reused password even if it has not been pwned.
+type Cluster struct {
+	Size int `json:"size"`
+	// Count is the number of times the password has been
+	// seen, as for Finding. It is zero if the password has
+	// not been pwned.
+	Count   int        `json:"count"`
+	Risk    pwned.Risk `json:"risk"`
+	Members []Member   `json:"members"`
+}
+
+// Report is the result of an audit. Findings are ordered
+// from the most to the least often seen password.
+//
+// Clusters are ordered with pwned passwords first, from the
+// most to the least often seen, then from the largest to
+// the smallest.
+type Report struct {
+	Accounts int       `json:"accounts"`
+	Prefixes int       `json:"prefixes"`
+	Findings []Finding `json:"findings"`
+	Clusters []Cluster `json:"clusters"`
+}
+
+// WriteJSON writes the report to w as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	findings := r.Findings
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	clusters := r.Clusters
+	if clusters == nil {
+		clusters = []Cluster{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(&Report{r.Accounts, r.Prefixes, findings, clusters})
+}
+
+// WriteCSV writes the findings to w as CSV with a header
+// row.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"user", "line", "count", "risk", "shared"})
+
+	for _, f := range r.Findings {
+		cw.Write([]string{
+			f.User,
+			strconv.Itoa(f.Line),
+			strconv.Itoa(f.Count),
+			f.Risk.String(),
+			strconv.Itoa(f.Shared),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteClustersCSV writes the clusters to w as CSV with a
+// header row. There is one row for each member, and
+// clusters are numbered from one in the order of the
+// Report.
+func (r *Report) WriteClustersCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"cluster", "size", "count", "risk", "user", "line"})
+
+	for i, c := range r.Clusters {
+		for _, m := range c.Members {
+			cw.Write([]string{
+				strconv.Itoa(i + 1),
+				strconv.Itoa(c.Size),
+				strconv.Itoa(c.Count),
+				c.Risk.String(),
+				m.User,
+				strconv.Itoa(m.Line),
+			})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/audit"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"golang.org/x/crypto/ssh/terminal"
+	"google.golang.org/grpc"
+)
+
+const auditUsage = `Audits a list of password hashes read from FILE or standard input.
+
+The -input-format flag selects the format of the list:
+  hashes        SHA1 hashes, one per line as either HASH or USER:HASH
+  secretsdump   NTLM hashes as output by secretsdump or pwdump
+  ldif          {SHA} userPassword attributes of an LDIF export
+  htpasswd      {SHA} passwords of an Apache htpasswd file
+Salted password schemes cannot be audited and are skipped.
+
+Each distinct hash prefix is queried once. A report of the accounts
+whose password has been pwned, and of the clusters of accounts that
+share a password, is written in the chosen format. The csv format
+only includes the accounts; -clusters writes the clusters as CSV to
+a separate file. The hashes themselves are never printed.
+
+Without -addr, SHA1 hashes are queried directly from -source and
+NTLM hashes from -ntlm-source. With -addr, NTLM hashes are queried
+from the server's -ntlm-dataset.
+
+The exit status is 0 if no password was found, 1 if any password
+was found and 2 if an error occurred.`
+
+// auditReaders maps each -input-format to its parser.
+var auditReaders = map[string]func(io.Reader) ([]audit.Account, error){
+	"hashes":      audit.Read,
+	"secretsdump": audit.ReadSecretsdump,
+	"ldif":        audit.ReadLDIF,
+	"htpasswd":    audit.ReadHtpasswd,
+}
+
+// auditConfig holds the flags of the audit subcommand.
+type auditConfig struct {
+	input, inputFormat string
+
+	addr, dataset, source string
+	ntlmDataset           string
+	ntlmSource            string
+
+	format, output string
+	clusters       string
+	concurrency    int
+	progress       bool
+}
+
+// auditCmd runs the audit subcommand and returns the exit
+// code.
+func auditCmd(args []string) int {
+	var cfg auditConfig
+
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	flags.StringVar(&cfg.inputFormat, "input-format", "hashes", "the format of the hash list: hashes, secretsdump, ldif or htpasswd")
+	flags.StringVar(&cfg.addr, "addr", "", "the address of a pwned server to query")
+	flags.StringVar(&cfg.dataset, "dataset", "", "the named dataset to query on the server")
+	flags.StringVar(&cfg.ntlmDataset, "ntlm-dataset", "", "the named dataset to query on the server for NTLM hashes")
+	flags.StringVar(&cfg.source, "source", "gateway", "the source to query directly when -addr is not set")
+	flags.StringVar(&cfg.ntlmSource, "ntlm-source", "gateway-ntlm", "the source to query directly for NTLM hashes when -addr is not set")
+	flags.StringVar(&cfg.format, "format", "json", "the report format: json or csv")
+	flags.StringVar(&cfg.output, "o", "", "the file to write the report to, instead of standard output")
+	flags.StringVar(&cfg.clusters, "clusters", "", "the file to write the shared password clusters to as CSV")
+	flags.IntVar(&cfg.concurrency, "concurrency", audit.DefaultConcurrency, "the number of prefixes to query concurrently")
+	flags.BoolVar(&cfg.progress, "progress", terminal.IsTerminal(int(os.Stderr.Fd())), "report progress on standard error")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s audit [flags] [FILE]\n\n%s\n\n", os.Args[0], auditUsage)
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "\n"+sourceUsage)
+		fmt.Fprintln(flags.Output(), "\n"+ntlmSourceUsage)
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || cfg.format != "json" && cfg.format != "csv" || auditReaders[cfg.inputFormat] == nil {
+		flags.Usage()
+		return exitFailed
+	}
+
+	cfg.input = flags.Arg(0)
+
+	found, err := runAudit(&cfg)
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+		return exitFailed
+	case found:
+		return exitPwned
+	default:
+		return exitOK
+	}
+}
+
+// runAudit audits the hash list and writes the report.
+// found is true if any account has a pwned password.
+func runAudit(cfg *auditConfig) (found bool, err error) {
+	in := io.Reader(os.Stdin)
+	if cfg.input != "" && cfg.input != "-" {
+		f, err := os.Open(cfg.input)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+
+		in = f
+	}
+
+	if cfg.progress {
+		fmt.Fprint(os.Stderr, "reading hash list...")
+	}
+
+	accounts, err := auditReaders[cfg.inputFormat](bufio.NewReaderSize(in, 1<<16))
+	if err != nil {
+		return false, err
+	}
+
+	if cfg.progress {
+		fmt.Fprintf(os.Stderr, "\rread %d accounts\n", len(accounts))
+	}
+
+	srcs, closer, err := openAuditSources(cfg, accounts)
+	if err != nil {
+		return false, err
+	}
+	defer closer.Close()
+
+	opts := []audit.Option{audit.WithConcurrency(cfg.concurrency)}
+	for alg, src := range srcs {
+		opts = append(opts, audit.WithSource(alg, src))
+	}
+
+	if cfg.progress {
+		var last time.Time
+		opts = append(opts, audit.WithProgress(func(p audit.Progress) {
+			if now := time.Now(); p.Done == p.Prefixes || now.Sub(last) >= time.Second/4 {
+				last = now
+				fmt.Fprintf(os.Stderr, "\rqueried %d of %d prefixes (%.1f%%)",
+					p.Done, p.Prefixes, 100*float64(p.Done)/float64(p.Prefixes))
+			}
+		}))
+	}
+
+	rep, err := audit.New(srcs[audit.SHA1], opts...).Audit(context.Background(), accounts)
+	if cfg.progress && len(accounts) > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if cfg.progress {
+		fmt.Fprintf(os.Stderr, "%d of %d accounts have pwned passwords\n", len(rep.Findings), rep.Accounts)
+		fmt.Fprintf(os.Stderr, "%d passwords are shared by more than one account\n", len(rep.Clusters))
+	}
+
+	if cfg.clusters != "" {
+		if err := writeFile(cfg.clusters, rep.WriteClustersCSV); err != nil {
+			return false, err
+		}
+	}
+
+	return len(rep.Findings) > 0, writeReport(rep, cfg.format, cfg.output)
+}
+
+// openAuditSources opens a source for each hash algorithm
+// used by accounts.
+func openAuditSources(cfg *auditConfig, accounts []audit.Account) (map[audit.Algorithm]pwned.Source, io.Closer, error) {
+	used := make(map[audit.Algorithm]bool)
+	for _, acct := range accounts {
+		used[acct.Algorithm] = true
+	}
+
+	srcs := make(map[audit.Algorithm]pwned.Source)
+
+	if cfg.addr == "" {
+		if cfg.dataset != "" || cfg.ntlmDataset != "" {
+			return nil, nil, fmt.Errorf("-dataset and -ntlm-dataset require -addr")
+		}
+
+		var closers multiCloser
+		for alg, open := range map[audit.Algorithm]func() (pwned.Ranger, error){
+			audit.SHA1: func() (pwned.Ranger, error) { return openSource(cfg.source) },
+			audit.NTLM: func() (pwned.Ranger, error) { return openNTLMSource(cfg.ntlmSource) },
+		} {
+			if !used[alg] {
+				continue
+			}
+
+			r, err := open()
+			if err != nil {
+				closers.Close()
+				return nil, nil, err
+			}
+
+			if c, ok := r.(io.Closer); ok {
+				closers = append(closers, c)
+			}
+
+			srcs[alg] = pwned.RangerSource(r)
+		}
+
+		return srcs, closers, nil
+	}
+
+	if used[audit.NTLM] && cfg.ntlmDataset == "" {
+		return nil, nil, fmt.Errorf("-ntlm-dataset is required to audit NTLM hashes with -addr")
+	}
+
+	cc, err := grpc.Dial(cfg.addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := pwnedgrpc.NewClient(cc)
+
+	var opts []grpc.CallOption
+	if cfg.dataset != "" {
+		opts = append(opts, pwnedgrpc.UseDataset(cfg.dataset))
+	}
+
+	srcs[audit.SHA1] = pwnedgrpc.ClientSource(c, opts...)
+	if cfg.ntlmDataset != "" {
+		srcs[audit.NTLM] = pwnedgrpc.ClientSource(c, pwnedgrpc.UseDataset(cfg.ntlmDataset))
+	}
+
+	return srcs, c, nil
+}
+
+// multiCloser closes each io.Closer in turn.
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var err error
+	for _, c := range m {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func writeReport(rep *audit.Report, format, output string) error {
+	write := rep.WriteJSON
+	if format == "csv" {
+		write = rep.WriteCSV
+	}
+
+	if output == "" {
+		bw := bufio.NewWriter(os.Stdout)
+		if err := write(bw); err != nil {
+			return err
+		}
+
+		return bw.Flush()
+	}
+
+	return writeFile(output, write)
+}
+
+// writeFile creates the named file and calls write with a
+// buffered writer for it.
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"golang.org/x/crypto/ssh/terminal"
+	"google.golang.org/grpc"
+)
+
+// Exit codes used by the subcommands that check passwords.
+const (
+	exitOK     = 0 // no password was found
+	exitPwned  = 1 // at least one password was found
+	exitFailed = 2 // an error occurred
+)
+
+const checkUsage = `Checks passwords against a pwned password list.
+
+When standard input is a terminal, check prompts for a single
+password without echoing it. Otherwise it reads one password per
+line from standard input. Passwords are never printed.
+
+Without -addr, the list is queried directly from -source.
+
+The exit status is 0 if no password was found, 1 if any password
+was found and 2 if an error occurred.`
+
+// checker checks a single password.
+type checker func(ctx context.Context, password string) (count int, err error)
+
+// check runs the check subcommand and returns the exit
+// code.
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	addr := flags.String("addr", "", "the address of a pwned server to query")
+	dataset := flags.String("dataset", "", "the named dataset to query on the server")
+	lookup := flags.Bool("lookup", false, "send the full password hash to the server rather than a range query")
+	source := flags.String("source", "gateway", "the source to query directly when -addr is not set")
+	asJSON := flags.Bool("json", false, "print results as JSON, one object per password")
+	timeout := flags.Duration("timeout", 30*time.Second, "the timeout for each password")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s check [flags]\n\n%s\n\n", os.Args[0], checkUsage)
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "\n"+sourceUsage)
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitFailed
+	}
+
+	check, closer, err := newChecker(*addr, *dataset, *source, *lookup)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+		return exitFailed
+	}
+	defer closer.Close()
+
+	next := readPasswords(os.Stdin)
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		next = promptPassword(int(os.Stdin.Fd()))
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	code := exitOK
+	for n := 1; ; n++ {
+		password, ok, err := next()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pwned: failed to read password: %v\n", err)
+			return exitFailed
+		}
+		if !ok {
+			break
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		count, err := check(ctx, password)
+		cancel()
+		if err != nil {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "pwned: failed to check password %d: %v\n", n, err)
+			return exitFailed
+		}
+
+		if count > 0 {
+			code = exitPwned
+		}
+
+		if err := printResult(out, *asJSON, n, count); err != nil {
+			fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+			return exitFailed
+		}
+	}
+
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+		return exitFailed
+	}
+
+	return code
+}
+
+func newChecker(addr, dataset, source string, lookup bool) (checker, io.Closer, error) {
+	if addr == "" {
+		if dataset != "" || lookup {
+			return nil, nil, fmt.Errorf("-dataset and -lookup require -addr")
+		}
+
+		r, err := openSource(source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		closer, ok := r.(io.Closer)
+		if !ok {
+			closer = nopCloser{}
+		}
+
+		return func(ctx context.Context, password string) (int, error) {
+			return pwned.Search(ctx, r, password)
+		}, closer, nil
+	}
+
+	cc, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := pwnedgrpc.NewClient(cc)
+
+	var opts []grpc.CallOption
+	if dataset != "" {
+		opts = append(opts, pwnedgrpc.UseDataset(dataset))
+	}
+
+	if lookup {
+		return func(ctx context.Context, password string) (int, error) {
+			return c.Lookup(ctx, password, opts...)
+		}, c, nil
+	}
+
+	return func(ctx context.Context, password string) (int, error) {
+		return c.Search(ctx, password, opts...)
+	}, c, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// readPasswords returns a function that reads one password
+// per line from r. Blank lines are skipped.
+func readPasswords(r io.Reader) func() (password string, ok bool, err error) {
+	s := bufio.NewScanner(r)
+	return func() (string, bool, error) {
+		for s.Scan() {
+			if password := strings.TrimSuffix(s.Text(), "\r"); password != "" {
+				return password, true, nil
+			}
+		}
+
+		return "", false, s.Err()
+	}
+}
+
+// promptPassword returns a function that prompts for a
+// single password on the terminal without echoing it.
+func promptPassword(fd int) func() (password string, ok bool, err error) {
+	var done bool
+	return func() (string, bool, error) {
+		if done {
+			return "", false, nil
+		}
+		done = true
+
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", false, err
+		}
+
+		return string(password), true, nil
+	}
+}
+
+func printResult(w io.Writer, asJSON bool, n, count int) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(struct {
+			Password int  `json:"password"`
+			Pwned    bool `json:"pwned"`
+			Count    int  `json:"count"`
+		}{n, count > 0, count})
+	}
+
+	var err error
+	switch count {
+	case 0:
+		_, err = fmt.Fprintf(w, "password %d: not found\n", n)
+	case 1:
+		_, err = fmt.Fprintf(w, "password %d: pwned, seen at least once\n", n)
+	default:
+		_, err = fmt.Fprintf(w, "password %d: pwned, seen at least %d times\n", n, count)
+	}
+
+	return err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.tmthrgd.dev/pwned/generate"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/wordlist"
+	"google.golang.org/grpc"
+)
+
+const generateUsage = `Generates diceware style passphrases, or random passwords with
+-password, that have not appeared in a pwned password list.
+
+Randomness comes from crypto/rand. Each candidate is checked against
+the list and regenerated until it is not found. The passwords are
+printed one per line, and their entropy on standard error.
+
+Without -addr, the list is queried directly from -source.`
+
+// generateCmd runs the generate subcommand and returns the
+// exit code.
+func generateCmd(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	password := flags.Bool("password", false, "generate random passwords rather than passphrases")
+	words := flags.Int("words", generate.DefaultWords, "the number of words in a passphrase")
+	sep := flags.String("sep", generate.DefaultSeparator, "the separator between the words of a passphrase")
+	capitalize := flags.Bool("capitalize", false, "capitalise each word of a passphrase")
+	list := flags.String("wordlist", "", "a file of words, one per line, to use instead of the EFF large wordlist")
+	length := flags.Int("length", generate.DefaultLength, "the length of a random password")
+	charset := flags.String("charset", generate.DefaultCharset, "the characters of a random password")
+	count := flags.Int("n", 1, "the number of passwords to generate")
+	attempts := flags.Int("max-attempts", generate.DefaultMaxAttempts, "the number of candidates to try for each password")
+	addr := flags.String("addr", "", "the address of a pwned server to query")
+	dataset := flags.String("dataset", "", "the named dataset to query on the server")
+	source := flags.String("source", "gateway", "the source to query directly when -addr is not set")
+	asJSON := flags.Bool("json", false, "print results as JSON, one object per password")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s generate [flags]\n\n%s\n\n", os.Args[0], generateUsage)
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "\n"+sourceUsage)
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitFailed
+	}
+
+	opts := []generate.Option{generate.WithMaxAttempts(*attempts)}
+	if *password {
+		opts = append(opts, generate.WithLength(*length), generate.WithCharset(*charset))
+	} else {
+		opts = append(opts, generate.WithWords(*words), generate.WithSeparator(*sep))
+		if *capitalize {
+			opts = append(opts, generate.WithCapitalize())
+		}
+
+		if *list != "" {
+			f, err := os.Open(*list)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+				return exitFailed
+			}
+
+			words, err := wordlist.Parse(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+				return exitFailed
+			}
+
+			opts = append(opts, generate.WithWordlist(words))
+		}
+	}
+
+	if err := runGenerate(opts, *password, *count, *addr, *dataset, *source, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+		return exitFailed
+	}
+
+	return exitOK
+}
+
+func runGenerate(opts []generate.Option, password bool, count int, addr, dataset, source string, asJSON bool) error {
+	if addr == "" {
+		if dataset != "" {
+			return fmt.Errorf("-dataset requires -addr")
+		}
+
+		r, err := openSource(source)
+		if err != nil {
+			return err
+		}
+
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+
+		opts = append(opts, generate.WithRanger(r))
+	} else {
+		cc, err := grpc.Dial(addr, grpc.WithInsecure())
+		if err != nil {
+			return err
+		}
+
+		c := pwnedgrpc.NewClient(cc)
+		defer c.Close()
+
+		var callOpts []grpc.CallOption
+		if dataset != "" {
+			callOpts = append(callOpts, pwnedgrpc.UseDataset(dataset))
+		}
+
+		opts = append(opts, generate.WithClient(c, callOpts...))
+	}
+
+	newGenerator := generate.Passphrase
+	if password {
+		newGenerator = generate.Password
+	}
+
+	g, err := newGenerator(opts...)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	for i := 0; i < count; i++ {
+		res, err := g.Generate(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			err = json.NewEncoder(out).Encode(res)
+		} else {
+			_, err = fmt.Fprintln(out, res.Password)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !asJSON {
+		fmt.Fprintf(os.Stderr, "%.1f bits of entropy\n", g.Entropy())
+	}
+
+	return out.Flush()
+}
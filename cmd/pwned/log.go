@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+type logLevel int32
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var logLevelNames = [...]string{
+	levelDebug: "debug",
+	levelInfo:  "info",
+	levelWarn:  "warn",
+	levelError: "error",
+}
+
+var currentLevel = int32(levelInfo)
+
+// setLogLevel sets the minimum level of messages that are
+// logged by name.
+func setLogLevel(name string) error {
+	for level, n := range logLevelNames {
+		if n == name {
+			atomic.StoreInt32(&currentLevel, int32(level))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown log level %q", name)
+}
+
+func logf(level logLevel, format string, v ...interface{}) {
+	if int32(level) >= atomic.LoadInt32(&currentLevel) {
+		log.Printf(logLevelNames[level]+": "+format, v...)
+	}
+}
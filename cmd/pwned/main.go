@@ -1,25 +1,26 @@
 package main
 
 import (
-	"flag"
-	"log"
-	"net"
-
-	"go.tmthrgd.dev/pwned/gateway"
-	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
-	"google.golang.org/grpc"
+	"os"
 )
 
 func main() {
-	addr := flag.String("addr", ":8080", "the address to listen on")
-	flag.Parse()
-
-	ln, err := net.Listen("tcp", *addr)
-	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "check":
+			os.Exit(check(os.Args[2:]))
+		case "audit":
+			os.Exit(auditCmd(os.Args[2:]))
+		case "vault":
+			os.Exit(vaultCmd(os.Args[2:]))
+		case "generate":
+			os.Exit(generateCmd(os.Args[2:]))
+		}
 	}
 
-	gs := grpc.NewServer()
-	pwnedgrpc.NewServer(gateway.New()).Attach(gs)
-	log.Fatal(gs.Serve(ln))
+	// For compatibility, serve is the default.
+	serve(os.Args[1:])
 }
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/middleware"
+	"go.tmthrgd.dev/pwned/swap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// serve runs the gRPC server.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "the address to listen on")
+	adminAddr := flags.String("admin-addr", "", "the address for the admin service to listen on, as host:port or unix:PATH, or empty to disable it")
+	adminCert := flags.String("admin-cert", "", "the TLS certificate file for the admin service")
+	adminKey := flags.String("admin-key", "", "the TLS key file for the admin service")
+	level := flags.String("log-level", "info", "the minimum level of messages to log: debug, info, warn or error")
+	source := flags.String("source", "gateway", "the source of the default dataset, or empty for none")
+	cacheSize := flags.Int("cache-size", 0, "the number of range results to cache for each dataset, or zero to disable caching")
+	cacheTTL := flags.Duration("cache-ttl", 0, "how long to cache range results for, or zero to keep them until the cache is full")
+	var datasets datasetsFlag
+	flags.Var(&datasets, "dataset", "a named dataset to serve as name=source, may be repeated")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [serve] [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "\n"+sourceUsage)
+		fmt.Fprintln(flags.Output(), "\nSending SIGHUP re-opens every dataset from its source.")
+		fmt.Fprintln(flags.Output(), "\nThe admin service requires the token in the "+adminTokenEnv+" environment variable.")
+		fmt.Fprintln(flags.Output(), "Unless -admin-cert and -admin-key are given, it must listen on a loopback")
+		fmt.Fprintln(flags.Output(), "address or a unix socket so that the token is not sent over the network in plaintext.")
+	}
+	flags.Parse(args)
+
+	if err := setLogLevel(*level); err != nil {
+		log.Fatal(err)
+	}
+
+	var adminToken string
+	if *adminAddr != "" {
+		if adminToken = os.Getenv(adminTokenEnv); adminToken == "" {
+			log.Fatalf("%s must be set to use the admin service", adminTokenEnv)
+		}
+	}
+
+	var cache pwned.Middleware
+	if *cacheSize > 0 {
+		cache = middleware.Cache(*cacheSize, *cacheTTL)
+	}
+
+	var opts []pwnedgrpc.ServerOption
+	seen := make(map[string]bool, len(datasets))
+	for _, ds := range datasets {
+		// pwnedgrpc.WithDataset panics on an empty name, and
+		// a later dataset would silently replace an earlier
+		// one with the same name.
+		switch {
+		case ds.name == "":
+			log.Fatalf("dataset %q has an empty name", ds.name+"="+ds.spec)
+		case seen[ds.name]:
+			log.Fatalf("dataset %q is given more than once", ds.name)
+		}
+		seen[ds.name] = true
+
+		r, err := openDataset(ds.name, ds.spec, cache)
+		if err != nil {
+			log.Fatalf("failed to open dataset %s: %v", ds.name, err)
+		}
+
+		opts = append(opts, pwnedgrpc.WithDataset(ds.name, r))
+		loaded = append(loaded, r)
+	}
+
+	var def pwned.Ranger
+	if *source != "" {
+		r, err := openDataset("", *source, cache)
+		if err != nil {
+			log.Fatalf("failed to open default dataset: %v", err)
+		}
+
+		def = r
+		loaded = append(loaded, r)
+	}
+
+	go reloadOnSignal()
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := pwnedgrpc.NewServer(def, opts...)
+
+	if *adminAddr != "" {
+		aln, aopts, err := listenAdmin(*adminAddr, *adminCert, *adminKey)
+		if err != nil {
+			log.Fatalf("failed to listen for admin service: %v", err)
+		}
+
+		ags := grpc.NewServer(aopts...)
+		pwnedgrpc.NewAdminServer(srv, adminToken,
+			pwnedgrpc.WithLogLevelHook(func(level string) error {
+				if err := setLogLevel(level); err != nil {
+					return err
+				}
+
+				logf(levelInfo, "log level set to %s", level)
+				return nil
+			})).Attach(ags)
+		go func() { log.Fatal(ags.Serve(aln)) }()
+	}
+
+	gs := grpc.NewServer()
+	srv.Attach(gs)
+	log.Fatal(gs.Serve(ln))
+}
+
+// adminTokenEnv is the environment variable that holds the
+// token for the admin service. It is not a flag so that it
+// is not visible to other users.
+const adminTokenEnv = "PWNED_ADMIN_TOKEN"
+
+// listenAdmin listens on the address of the admin service.
+// As the admin token would otherwise be sent in plaintext,
+// TCP addresses must be loopback addresses unless a TLS
+// certificate and key are given.
+func listenAdmin(addr, certFile, keyFile string) (net.Listener, []grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if certFile != "" || keyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	if strings.HasPrefix(addr, "unix:") {
+		ln, err := net.Listen("unix", strings.TrimPrefix(addr, "unix:"))
+		return ln, opts, err
+	}
+
+	if opts == nil {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, nil, fmt.Errorf("%s is not a loopback address, use -admin-cert and -admin-key to serve the admin service over TLS", addr)
+		}
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	return ln, opts, err
+}
+
+// loadedDataset is a dataset that can be re-opened from its
+// source. It implements pwnedgrpc.Reloader.
+type loadedDataset struct {
+	*swap.Ranger
+	name, spec string
+
+	// cache, if not nil, wraps each source that is opened
+	// so that a reloaded source starts with an empty cache.
+	cache pwned.Middleware
+}
+
+var loaded []*loadedDataset
+
+func openDataset(name, spec string, cache pwned.Middleware) (*loadedDataset, error) {
+	ds := &loadedDataset{name: name, spec: spec, cache: cache}
+
+	r, err := ds.open()
+	if err != nil {
+		return nil, err
+	}
+
+	ds.Ranger = swap.New(r)
+	return ds, nil
+}
+
+// open opens the source of the dataset and wraps it with
+// the cache.
+func (ds *loadedDataset) open() (pwned.Ranger, error) {
+	r, err := openSource(ds.spec)
+	if err != nil || ds.cache == nil {
+		return r, err
+	}
+
+	return cachedSource{ds.cache(r), r}, nil
+}
+
+// Reload re-opens the dataset from its source and swaps it
+// in place of the existing one. Sources that cannot be
+// opened twice are closed before they are re-opened.
+func (ds *loadedDataset) Reload() error {
+	var err error
+	if exclusiveSource(ds.spec) {
+		err = ds.Reopen(context.Background(), ds.open)
+	} else {
+		var r pwned.Ranger
+		if r, err = ds.open(); err == nil {
+			err = ds.Swap(context.Background(), r)
+		}
+	}
+
+	if err != nil {
+		logf(levelError, "failed to reload dataset %q: %v", ds.name, err)
+		return err
+	}
+
+	logf(levelInfo, "reloaded dataset %q", ds.name)
+	return nil
+}
+
+func reloadOnSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+
+	for range c {
+		for _, ds := range loaded {
+			ds.Reload()
+		}
+	}
+}
+
+// cachedSource is a source wrapped by middleware.Cache. The
+// cache implements pwnedgrpc.Lookup, pwnedgrpc.Cache,
+// pwnedgrpc.Truncated and pwned.InfoRanger, but it does not
+// close the source, which swap.Ranger relies on.
+type cachedSource struct {
+	cache  pwned.Ranger
+	source pwned.Ranger
+}
+
+func (c cachedSource) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return c.cache.Range(ctx, prefix)
+}
+
+func (c cachedSource) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	return c.cache.(pwnedgrpc.Lookup).Lookup(ctx, digest)
+}
+
+func (c cachedSource) TruncatedBits() int {
+	return c.cache.(pwnedgrpc.Truncated).TruncatedBits()
+}
+
+func (c cachedSource) Info(ctx context.Context) (*pwned.Info, error) {
+	return c.cache.(pwned.InfoRanger).Info(ctx)
+}
+
+func (c cachedSource) Purge() int {
+	return c.cache.(pwnedgrpc.Cache).Purge()
+}
+
+func (c cachedSource) CacheStats() (hits, misses uint64) {
+	return c.cache.(pwnedgrpc.Cache).CacheStats()
+}
+
+func (c cachedSource) Close() error {
+	if cl, ok := c.source.(io.Closer); ok {
+		return cl.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/gateway"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/internal/test"
+	"go.tmthrgd.dev/pwned/middleware"
+	"go.tmthrgd.dev/pwned/store/boltdb"
+)
+
+func TestReloadBolt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pwned-serve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ds, err := openDataset("bolt", "bolt:"+filepath.Join(dir, "pwned.db"), nil)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	digest := sha1.Sum([]byte("password"))
+	require.NoError(t, ds.Current().(*boltdb.Store).Add(digest, 8))
+
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- ds.Reload() }()
+
+	select {
+	case err := <-reloaded:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Reload did not return")
+	}
+
+	count, err := ds.Lookup(context.Background(), digest)
+	require.NoError(t, err)
+	assert.Equal(t, 8, count)
+}
+
+func TestServeCache(t *testing.T) {
+	f, err := ioutil.TempFile("", "pwned-serve")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("password\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	ds, err := openDataset("", "wordlist:"+f.Name(), middleware.Cache(16, 0))
+	require.NoError(t, err)
+	defer ds.Close()
+
+	srv := pwnedgrpc.NewServer(ds)
+	admin := pwnedgrpc.NewAdminServer(srv, "secret")
+
+	sc, stop := test.TestingClient(srv.Attach)
+	defer stop()
+
+	ac, stop := test.TestingClient(admin.Attach)
+	defer stop()
+
+	c := pwnedgrpc.NewClient(sc)
+	for i := 0; i < 2; i++ {
+		count, err := c.Search(context.Background(), "password")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+
+	cc := pwnedgrpc.NewAdminClient(ac, "secret")
+
+	stats, err := cc.Stats(context.Background())
+	require.NoError(t, err)
+	require.Len(t, stats.Datasets, 1)
+	assert.True(t, stats.Datasets[0].Cache)
+	assert.Equal(t, uint64(1), stats.Datasets[0].CacheHits)
+	assert.Equal(t, uint64(1), stats.Datasets[0].CacheMisses)
+
+	purged, err := cc.PurgeCache(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	require.NoError(t, cc.ReloadDataset(context.Background(), ""))
+
+	stats, err = cc.Stats(context.Background())
+	require.NoError(t, err)
+	assert.True(t, stats.Datasets[0].Cache)
+	assert.Equal(t, uint64(0), stats.Datasets[0].CacheHits)
+}
+
+func TestListenAdmin(t *testing.T) {
+	for _, addr := range []string{":0", "0.0.0.0:0", "192.0.2.1:0", "example.com:0"} {
+		_, _, err := listenAdmin(addr, "", "")
+		assert.Error(t, err, addr)
+	}
+
+	for _, addr := range []string{"127.0.0.1:0", "localhost:0"} {
+		ln, _, err := listenAdmin(addr, "", "")
+		require.NoError(t, err, addr)
+		ln.Close()
+	}
+
+	dir, err := ioutil.TempDir("", "pwned-serve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ln, _, err := listenAdmin("unix:"+filepath.Join(dir, "admin.sock"), "", "")
+	require.NoError(t, err)
+	ln.Close()
+
+	_, _, err = listenAdmin(":0", filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"))
+	assert.Error(t, err)
+}
+
+func TestOpenSourceVersion(t *testing.T) {
+	for spec, version := range map[string]string{
+		"gateway":            gateway.DefaultVersion,
+		"gateway,version=v8": "v8",
+		"gateway,version=v8:http://localhost/range/{prefix}": "v8",
+	} {
+		r, err := openSource(spec)
+		require.NoError(t, err, spec)
+
+		info, err := r.(pwned.InfoRanger).Info(context.Background())
+		require.NoError(t, err, spec)
+		assert.Equal(t, version, info.Version, spec)
+	}
+
+	for _, spec := range []string{
+		"gateway,v8",
+		"gateway-ntlm",
+		"dir,version=v8",
+	} {
+		_, err := openSource(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/gateway"
+	"go.tmthrgd.dev/pwned/passwords"
+	"go.tmthrgd.dev/pwned/rangefiles"
+	"go.tmthrgd.dev/pwned/store/boltdb"
+	"go.tmthrgd.dev/pwned/store/eliasfano"
+	"go.tmthrgd.dev/pwned/store/truncated"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+const sourceUsage = `Sources are one of:
+  gateway[:URL]         the ‘Have I been pwned?’ API, or a compatible endpoint
+  dir:PATH              a directory of per-prefix range files
+  zip:PATH              a zip archive of per-prefix range files
+  tar:PATH              an uncompressed tar archive of per-prefix range files
+  wordlist:PATH         a list of passwords, one per line
+  bolt:PATH             a bbolt password store
+  eliasfano:PATH        an Elias-Fano coded copy of an ordered-by-hash dataset
+  truncated:BITS:PATH   a truncated copy of an ordered-by-hash dataset
+
+The version of a dataset, such as v8 or the date it was downloaded,
+may be recorded by adding ,version=VERSION to the source's kind, as
+in dir,version=2019-07-16:PATH. It is reported by the Info RPC. A
+bolt store keeps the version it is given.`
+
+const ntlmSourceUsage = `NTLM sources are:
+  gateway-ntlm[:URL]    the ‘Have I been pwned?’ API for NTLM hashes, or a
+                        compatible endpoint`
+
+// sourceSpec is a parsed source description of the form
+// KIND[,version=VERSION][:ARG].
+type sourceSpec struct {
+	kind, version, arg string
+}
+
+func parseSpec(spec string) (sourceSpec, error) {
+	var s sourceSpec
+	s.kind = spec
+	if idx := strings.IndexByte(spec, ':'); idx >= 0 {
+		s.kind, s.arg = spec[:idx], spec[idx+1:]
+	}
+
+	if idx := strings.IndexByte(s.kind, ','); idx >= 0 {
+		opt := s.kind[idx+1:]
+		s.kind = s.kind[:idx]
+
+		if !strings.HasPrefix(opt, "version=") {
+			return s, fmt.Errorf("unknown option %q for source %q", opt, s.kind)
+		}
+
+		s.version = strings.TrimPrefix(opt, "version=")
+	}
+
+	return s, nil
+}
+
+// openNTLMSource opens the pwned.Ranger of NTLM hashes
+// described by spec.
+func openNTLMSource(spec string) (pwned.Ranger, error) {
+	s, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.kind {
+	case "gateway-ntlm":
+		return openGateway(s, gateway.WithNTLM()), nil
+	default:
+		return nil, fmt.Errorf("unknown NTLM source %q", s.kind)
+	}
+}
+
+// openSource opens the pwned.Ranger of SHA1 hashes
+// described by spec.
+func openSource(spec string) (pwned.Ranger, error) {
+	s, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, arg := s.kind, s.arg
+	if kind != "gateway" && kind != "gateway-ntlm" && arg == "" {
+		return nil, fmt.Errorf("source %q requires a path", kind)
+	}
+
+	var rangeOpts []rangefiles.Option
+	if s.version != "" {
+		rangeOpts = append(rangeOpts, rangefiles.WithVersion(s.version))
+	}
+
+	switch kind {
+	case "gateway":
+		return openGateway(s), nil
+	case "gateway-ntlm":
+		// Searching it for SHA1 hashes would never find
+		// anything.
+		return nil, fmt.Errorf("source %q serves NTLM hashes and can only be used with audit -ntlm-source", kind)
+	case "dir":
+		return rangefiles.NewDir(arg, rangeOpts...), nil
+	case "zip":
+		return rangefiles.OpenZip(arg, rangeOpts...)
+	case "tar":
+		return rangefiles.OpenTar(arg, rangeOpts...)
+	case "wordlist":
+		return wordlist.Load(arg, wordlist.WithVersion(s.version))
+	case "bolt":
+		return openBolt(arg, s.version)
+	case "eliasfano":
+		return buildStore(arg, func(r *passwords.Reader) (pwned.Ranger, error) {
+			return eliasfano.Build(r, eliasfano.WithVersion(s.version))
+		})
+	case "truncated":
+		idx := strings.IndexByte(arg, ':')
+		if idx < 0 {
+			return nil, fmt.Errorf("source %q requires a number of bits and a path", kind)
+		}
+
+		bits, err := strconv.Atoi(arg[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of bits %q", arg[:idx])
+		}
+
+		return buildStore(arg[idx+1:], func(r *passwords.Reader) (pwned.Ranger, error) {
+			return truncated.Build(r, bits, truncated.WithVersion(s.version))
+		})
+	default:
+		return nil, fmt.Errorf("unknown source %q", kind)
+	}
+}
+
+func openGateway(s sourceSpec, opts ...gateway.Option) pwned.Ranger {
+	if s.arg != "" {
+		opts = append(opts, gateway.WithEndpoint(s.arg))
+	}
+
+	if s.version != "" {
+		opts = append(opts, gateway.WithVersion(s.version))
+	}
+
+	return gateway.New(opts...)
+}
+
+func openBolt(path, version string) (pwned.Ranger, error) {
+	s, err := boltdb.Open(path, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		return s, nil
+	}
+
+	if err := s.SetVersion(version); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// exclusiveSource reports whether the source described by
+// spec locks its file, so that it cannot be opened again
+// until it has been closed.
+func exclusiveSource(spec string) bool {
+	s, err := parseSpec(spec)
+	return err == nil && s.kind == "bolt"
+}
+
+func buildStore(path string, build func(*passwords.Reader) (pwned.Ranger, error)) (pwned.Ranger, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return build(passwords.NewDatasetReader(f))
+}
+
+// datasetsFlag collects name=source pairs.
+type datasetsFlag []struct{ name, spec string }
+
+func (d *datasetsFlag) String() string {
+	specs := make([]string, len(*d))
+	for i, ds := range *d {
+		specs[i] = ds.name + "=" + ds.spec
+	}
+
+	return strings.Join(specs, ",")
+}
+
+func (d *datasetsFlag) Set(value string) error {
+	idx := strings.IndexByte(value, '=')
+	if idx <= 0 {
+		return fmt.Errorf("dataset %q must be of the form name=source", value)
+	}
+
+	*d = append(*d, struct{ name, spec string }{value[:idx], value[idx+1:]})
+	return nil
+}
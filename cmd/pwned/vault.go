@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/vault"
+	"google.golang.org/grpc"
+)
+
+const vaultUsage = `Checks the passwords of a password manager export, read from FILE or
+standard input, against a pwned password list.
+
+The -format flag selects the format of the export:
+  bitwarden-csv    a Bitwarden CSV export
+  bitwarden-json   an unencrypted Bitwarden JSON export
+  keepass-csv      a KeePassXC or KeePass 2 CSV export
+  1password-csv    a 1Password CSV export
+  1password-json   a 1Password 1PUX export, or its export.data file
+
+Passwords are only checked with range queries, never with full hash
+lookups, and are never printed. The pwned items are listed by name,
+folder and username.
+
+Without -addr, the list is queried directly from -source.
+
+The exit status is 0 if no password was found, 1 if any password
+was found and 2 if an error occurred.`
+
+// vaultReaders maps each -format to its parser.
+var vaultReaders = map[string]func(io.Reader) ([]vault.Item, error){
+	"bitwarden-csv":  vault.ReadBitwardenCSV,
+	"bitwarden-json": vault.ReadBitwardenJSON,
+	"keepass-csv":    vault.ReadKeePassCSV,
+	"1password-csv":  vault.Read1PasswordCSV,
+	"1password-json": vault.Read1PasswordJSON,
+}
+
+// vaultCmd runs the vault subcommand and returns the exit
+// code.
+func vaultCmd(args []string) int {
+	flags := flag.NewFlagSet("vault", flag.ExitOnError)
+	format := flags.String("format", "", "the format of the export (required)")
+	addr := flags.String("addr", "", "the address of a pwned server to query")
+	dataset := flags.String("dataset", "", "the named dataset to query on the server")
+	source := flags.String("source", "gateway", "the source to query directly when -addr is not set")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s vault -format FORMAT [flags] [FILE]\n\n%s\n\n", os.Args[0], vaultUsage)
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "\n"+sourceUsage)
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || vaultReaders[*format] == nil {
+		flags.Usage()
+		return exitFailed
+	}
+
+	found, err := runVault(flags.Arg(0), *format, *addr, *dataset, *source, *asJSON)
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "pwned: %v\n", err)
+		return exitFailed
+	case found:
+		return exitPwned
+	default:
+		return exitOK
+	}
+}
+
+// runVault checks the export in input and prints the
+// report. found is true if any item has a pwned password.
+func runVault(input, format, addr, dataset, source string, asJSON bool) (found bool, err error) {
+	items, err := readVault(input, format)
+	if err != nil {
+		return false, err
+	}
+
+	search, closer, err := newVaultSearch(addr, dataset, source)
+	if err != nil {
+		return false, err
+	}
+	defer closer.Close()
+
+	rep, err := vault.Check(context.Background(), search, items)
+	if err != nil {
+		return false, err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if asJSON {
+		err = rep.WriteJSON(out)
+	} else {
+		err = rep.WriteText(out)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return len(rep.Pwned) > 0, out.Flush()
+}
+
+func readVault(input, format string) ([]vault.Item, error) {
+	if input == "" || input == "-" {
+		return vaultReaders[format](os.Stdin)
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if format == "1password-json" && strings.EqualFold(filepath.Ext(input), ".1pux") {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		return vault.Read1PUX(f, fi.Size())
+	}
+
+	return vaultReaders[format](bufio.NewReader(f))
+}
+
+func newVaultSearch(addr, dataset, source string) (vault.SearchFunc, io.Closer, error) {
+	if addr == "" {
+		if dataset != "" {
+			return nil, nil, fmt.Errorf("-dataset requires -addr")
+		}
+
+		r, err := openSource(source)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		closer, ok := r.(io.Closer)
+		if !ok {
+			closer = nopCloser{}
+		}
+
+		return vault.RangerSearch(r), closer, nil
+	}
+
+	cc, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := pwnedgrpc.NewClient(cc)
+
+	var opts []grpc.CallOption
+	if dataset != "" {
+		opts = append(opts, pwnedgrpc.UseDataset(dataset))
+	}
+
+	return vault.ClientSearch(c, opts...), c, nil
+}
@@ -0,0 +1,236 @@
+// Package fallback provides a pwned.Ranger that falls back
+// to a secondary Ranger when the primary is slow or fails.
+package fallback
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+// Source identifies which Ranger served a response.
+type Source int
+
+const (
+	// Primary is the first Ranger passed to New.
+	Primary Source = iota
+	// Secondary is the second Ranger passed to New.
+	Secondary
+)
+
+func (s Source) String() string {
+	switch s {
+	case Primary:
+		return "primary"
+	case Secondary:
+		return "secondary"
+	default:
+		return fmt.Sprintf("Source(%d)", int(s))
+	}
+}
+
+// Observer is called once for every response with the
+// Source that served it. If both Rangers failed, it is
+// called with the Source that failed last and the error
+// returned to the caller.
+type Observer func(ctx context.Context, src Source, err error)
+
+type fallback struct {
+	rangers [2]pwned.Ranger
+
+	timeout  time.Duration
+	hedge    time.Duration
+	observer Observer
+}
+
+type lookupFallback struct {
+	fallback
+	lookups [2]pwnedgrpc.Lookup
+}
+
+// New returns a pwned.Ranger that queries primary and,
+// if that fails or times out, queries secondary.
+//
+// If both primary and secondary implement
+// pwnedgrpc.Lookup, the returned Ranger will also
+// implement it.
+func New(primary, secondary pwned.Ranger, opts ...Option) pwned.Ranger {
+	f := fallback{
+		rangers: [2]pwned.Ranger{primary, secondary},
+	}
+
+	for _, opt := range opts {
+		opt(&f)
+	}
+
+	pl, ok1 := primary.(pwnedgrpc.Lookup)
+	sl, ok2 := secondary.(pwnedgrpc.Lookup)
+	if ok1 && ok2 {
+		return &lookupFallback{f, [2]pwnedgrpc.Lookup{pl, sl}}
+	}
+
+	return &f
+}
+
+type result struct {
+	v   interface{}
+	err error
+	src Source
+}
+
+// do calls fn with the primary Source and, if required,
+// the secondary Source. It returns the first successful
+// result.
+func (f *fallback) do(ctx context.Context, fn func(ctx context.Context, src Source) (interface{}, error)) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	primaryCtx, primaryCancel := context.WithCancel(ctx)
+	defer primaryCancel()
+
+	results := make(chan result, 2)
+	start := func(ctx context.Context, src Source) {
+		go func() {
+			v, err := fn(ctx, src)
+			results <- result{v, err, src}
+		}()
+	}
+
+	start(primaryCtx, Primary)
+	running := [2]bool{Primary: true}
+
+	var timeout, hedge <-chan time.Time
+	if f.timeout > 0 {
+		t := time.NewTimer(f.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	if f.hedge > 0 {
+		t := time.NewTimer(f.hedge)
+		defer t.Stop()
+		hedge = t.C
+	}
+
+	var (
+		errs      [2]error
+		last      = Primary
+		secondary bool
+	)
+	for {
+		// Every event, other than a result from the
+		// secondary Ranger, requires the secondary Ranger
+		// to be started.
+		select {
+		case <-timeout:
+			timeout = nil
+
+			// The primary Ranger is abandoned, though it may
+			// still respond successfully before the
+			// secondary Ranger does.
+			primaryCancel()
+			running[Primary] = false
+			errs[Primary] = fmt.Errorf("timed out after %s", f.timeout)
+		case <-hedge:
+			hedge = nil
+		case res := <-results:
+			if res.err == nil {
+				f.observe(ctx, res.src, nil)
+				return res.v, nil
+			}
+
+			if res.src == Primary {
+				// The primary Ranger can no longer time out.
+				timeout = nil
+			}
+
+			running[res.src] = false
+			if errs[res.src] == nil {
+				errs[res.src] = res.err
+			}
+
+			last = res.src
+		}
+
+		if !secondary && ctx.Err() == nil {
+			start(ctx, Secondary)
+			running[Secondary], secondary = true, true
+		}
+
+		if running[Primary] || running[Secondary] {
+			continue
+		}
+
+		err := fmt.Errorf("pwned/fallback: primary returned error: %v", errs[Primary])
+		if secondary {
+			err = fmt.Errorf("pwned/fallback: primary returned error: %v; secondary returned error: %v",
+				errs[Primary], errs[Secondary])
+		}
+
+		f.observe(ctx, last, err)
+		return nil, err
+	}
+}
+
+func (f *fallback) observe(ctx context.Context, src Source, err error) {
+	if f.observer != nil {
+		f.observer(ctx, src, err)
+	}
+}
+
+func (f *fallback) Range(ctx context.Context, prefix string) ([]byte, error) {
+	v, err := f.do(ctx, func(ctx context.Context, src Source) (interface{}, error) {
+		return f.rangers[src].Range(ctx, prefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]byte), nil
+}
+
+func (f *lookupFallback) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	v, err := f.do(ctx, func(ctx context.Context, src Source) (interface{}, error) {
+		return f.lookups[src].Lookup(ctx, digest)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return v.(int), nil
+}
+
+// Option allows the behaviour of the fallback Ranger to be
+// configured.
+type Option func(*fallback)
+
+// WithTimeout sets how long to wait for the primary Ranger
+// before cancelling it and querying the secondary Ranger.
+// By default there is no timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(f *fallback) {
+		f.timeout = d
+	}
+}
+
+// WithHedge enables hedged requests. If the primary Ranger
+// has not responded within d, the secondary Ranger is
+// queried as well and whichever successfully responds
+// first is used. Unlike WithTimeout, the primary Ranger is
+// not cancelled. By default requests are not hedged.
+func WithHedge(d time.Duration) Option {
+	return func(f *fallback) {
+		f.hedge = d
+	}
+}
+
+// WithObserver sets a function that is called with the
+// Source that served each response.
+func WithObserver(fn Observer) Option {
+	return func(f *fallback) {
+		f.observer = fn
+	}
+}
@@ -0,0 +1,119 @@
+package fallback
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+type ranger struct {
+	res   string
+	err   error
+	delay time.Duration
+}
+
+func (r *ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	select {
+	case <-time.After(r.delay):
+		return []byte(r.res), r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type lookupRanger struct{ ranger }
+
+func (r *lookupRanger) Lookup(ctx context.Context, digest [sha1.Size]byte) (int, error) {
+	res, err := r.Range(ctx, "")
+	return len(res), err
+}
+
+type observed struct {
+	mu  sync.Mutex
+	src []Source
+}
+
+func (o *observed) observe(ctx context.Context, src Source, err error) {
+	o.mu.Lock()
+	o.src = append(o.src, src)
+	o.mu.Unlock()
+}
+
+func TestFallback(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name               string
+		primary, secondary ranger
+		opts               []Option
+		res                string
+		src                Source
+		err                bool
+	}{
+		{"primary", ranger{res: "primary"}, ranger{res: "secondary"}, nil, "primary", Primary, false},
+		{"error", ranger{err: errors.New("failed")}, ranger{res: "secondary"}, nil, "secondary", Secondary, false},
+		{"both error", ranger{err: errors.New("failed")}, ranger{err: errors.New("failed")}, nil, "", Secondary, true},
+		{"timeout", ranger{res: "primary", delay: time.Second}, ranger{res: "secondary"},
+			[]Option{WithTimeout(10 * time.Millisecond)}, "secondary", Secondary, false},
+		{"hedge", ranger{res: "primary", delay: time.Second}, ranger{res: "secondary"},
+			[]Option{WithHedge(10 * time.Millisecond)}, "secondary", Secondary, false},
+		{"hedge primary wins", ranger{res: "primary", delay: 50 * time.Millisecond}, ranger{res: "secondary", delay: time.Second},
+			[]Option{WithHedge(10 * time.Millisecond)}, "primary", Primary, false},
+		{"hedge secondary error", ranger{res: "primary", delay: 50 * time.Millisecond}, ranger{err: errors.New("failed")},
+			[]Option{WithHedge(10 * time.Millisecond)}, "primary", Primary, false},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var o observed
+			f := New(&tc.primary, &tc.secondary, append(tc.opts, WithObserver(o.observe))...)
+
+			res, err := f.Range(context.Background(), "00000")
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.res, string(res))
+			}
+
+			assert.Equal(t, []Source{tc.src}, o.src)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	f := New(&ranger{}, &lookupRanger{})
+	_, ok := f.(pwnedgrpc.Lookup)
+	assert.False(t, ok)
+
+	f = New(&lookupRanger{ranger{err: errors.New("failed")}}, &lookupRanger{ranger{res: "abc"}})
+	l, ok := f.(pwnedgrpc.Lookup)
+	require.True(t, ok)
+
+	count, err := l.Lookup(context.Background(), [sha1.Size]byte{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestTimeoutAfterError(t *testing.T) {
+	t.Parallel()
+
+	f := New(&ranger{err: errors.New("primary failed")},
+		&ranger{err: errors.New("secondary failed"), delay: 50 * time.Millisecond},
+		WithTimeout(10*time.Millisecond))
+
+	_, err := f.Range(context.Background(), "00000")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "primary failed")
+	assert.NotContains(t, err.Error(), "timed out")
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 
 	"go.tmthrgd.dev/pwned"
 	"go.tmthrgd.dev/pwned/passwords"
@@ -22,10 +23,18 @@ var defaultEndpoint = &url.URL{
 type gateway struct {
 	http     *http.Client
 	endpoint *url.URL
+
+	ntlm    bool
+	version string
 }
 
+// DefaultVersion is the version reported by Info. The API
+// always serves the most recent version of the list.
+const DefaultVersion = "latest"
+
 // New returns a pwned.Ranger that queries the ‘Have I been
-// pwned?’ APIv2 with range queries.
+// pwned?’ APIv2 with range queries. It also implements
+// pwned.RangeAppender and pwned.InfoRanger.
 //
 // It does not implement pwned.Lookup, and thus the full
 // password hash will never be sent to the ‘Have I been
@@ -36,6 +45,7 @@ func New(opts ...Option) pwned.Ranger {
 	g := &gateway{
 		http:     http.DefaultClient,
 		endpoint: defaultEndpoint,
+		version:  DefaultVersion,
 	}
 
 	for _, opt := range opts {
@@ -45,13 +55,39 @@ func New(opts ...Option) pwned.Ranger {
 	return g
 }
 
+// readerPool and ntlmReaderPool hold *passwords.Reader's
+// for reuse.
+var (
+	readerPool = sync.Pool{
+		New: func() interface{} {
+			return passwords.NewResultsReader(nil, "")
+		},
+	}
+	ntlmReaderPool = sync.Pool{
+		New: func() interface{} {
+			return passwords.NewNTLMResultsReader(nil, "")
+		},
+	}
+)
+
 func (g *gateway) Range(ctx context.Context, prefix string) ([]byte, error) {
+	const smallest = 381
+	return g.RangeAppend(ctx, make([]byte, 0, pwned.Size(smallest)), prefix)
+}
+
+func (g *gateway) RangeAppend(ctx context.Context, dst []byte, prefix string) ([]byte, error) {
 	endpoint := new(url.URL)
 	*endpoint = *g.endpoint
 
 	endpoint.Path = strings.Replace(endpoint.Path, "{prefix}", prefix, -1)
 	endpoint.RawQuery = strings.Replace(endpoint.RawQuery, "{prefix}", prefix, -1)
 
+	if g.ntlm {
+		query := endpoint.Query()
+		query.Set("mode", "ntlm")
+		endpoint.RawQuery = query.Encode()
+	}
+
 	resp, err := g.http.Do((&http.Request{
 		Method: http.MethodGet,
 		URL:    endpoint,
@@ -73,21 +109,43 @@ func (g *gateway) Range(ctx context.Context, prefix string) ([]byte, error) {
 			resp.StatusCode, resp.Status)
 	}
 
-	const smallest = 381
-	set := make([]byte, 0, pwned.Size(smallest))
+	pool := &readerPool
+	if g.ntlm {
+		pool = &ntlmReaderPool
+	}
 
-	r := passwords.NewResultsReader(resp.Body, prefix)
+	r := pool.Get().(*passwords.Reader)
+	r.Reset(resp.Body, prefix)
+	defer func() {
+		r.Reset(nil, "")
+		pool.Put(r)
+	}()
 
 	for r.Scan() {
 		_, suffix, count := r.Entry()
-		set = pwned.AppendResult(set, suffix, count)
+		dst = pwned.AppendResult(dst, suffix, count)
 	}
 
 	if r.Err() != nil {
 		return nil, fmt.Errorf("pwned/gateway: reader returned error: %v", r.Err())
 	}
 
-	return set, nil
+	return dst, nil
+}
+
+// Info implements pwned.InfoRanger. The number of entries
+// and prefixes is never known.
+func (g *gateway) Info(ctx context.Context) (*pwned.Info, error) {
+	algorithm := "SHA1"
+	if g.ntlm {
+		algorithm = "NTLM"
+	}
+
+	return &pwned.Info{
+		Version:   g.version,
+		Algorithm: algorithm,
+		Encoding:  "range-api",
+	}, nil
 }
 
 // Option allows the behaviour of the gateway to be
@@ -120,3 +178,25 @@ func WithEndpoint(endpoint string) Option {
 		g.endpoint = url
 	}
 }
+
+// WithNTLM makes the gateway query NTLM hashes, rather than
+// SHA1 hashes, by adding mode=ntlm to the query string.
+//
+// NTLM hashes are shorter than SHA1 hashes. The suffixes it
+// returns are padded with zeros, as described by
+// passwords.NewNTLMResultsReader, and NTLM digests must be
+// padded the same way before searching the results.
+func WithNTLM() Option {
+	return func(g *gateway) {
+		g.ntlm = true
+	}
+}
+
+// WithVersion sets the version of the dataset served by
+// the endpoint, as reported by Info. It defaults to
+// DefaultVersion.
+func WithVersion(version string) Option {
+	return func(g *gateway) {
+		g.version = version
+	}
+}
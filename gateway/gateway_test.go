@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
 	"go.tmthrgd.dev/pwned/grpc"
 	"go.tmthrgd.dev/pwned/internal/test"
 )
@@ -40,3 +41,17 @@ func TestSearchNotPresent(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, count)
 }
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	info, err := New().(pwned.InfoRanger).Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, DefaultVersion, info.Version)
+	assert.Equal(t, "SHA1", info.Algorithm)
+
+	info, err = New(WithNTLM(), WithVersion("v8")).(pwned.InfoRanger).Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v8", info.Version)
+	assert.Equal(t, "NTLM", info.Algorithm)
+}
@@ -0,0 +1,321 @@
+// Package generate creates random passphrases and passwords
+// that have not appeared in a pwned password list.
+//
+// Randomness comes from crypto/rand. Candidates that are
+// found in the list, or that fail a policy, are discarded
+// and a new candidate is generated.
+package generate
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/policy"
+	"google.golang.org/grpc"
+)
+
+// The defaults used by Passphrase and Password.
+const (
+	// DefaultWords is the number of words in a passphrase.
+	// With the EFF large wordlist, it gives 77 bits of
+	// entropy.
+	DefaultWords = 6
+	// DefaultSeparator separates the words of a
+	// passphrase.
+	DefaultSeparator = "-"
+	// DefaultLength is the length of a random password.
+	DefaultLength = 20
+	// DefaultCharset is the set of characters in a random
+	// password.
+	DefaultCharset = "abcdefghijklmnopqrstuvwxyz" +
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+		"0123456789" +
+		"!#$%&*+-=?@^_~"
+	// DefaultMaxAttempts is the number of candidates that
+	// are generated before giving up.
+	DefaultMaxAttempts = 10
+)
+
+// ErrTooManyAttempts is returned when every candidate has
+// been rejected.
+var ErrTooManyAttempts = errors.New("pwned/generate: every candidate was rejected")
+
+// Result is a generated password.
+type Result struct {
+	Password string `json:"password"`
+	// Entropy is the entropy, in bits, of the scheme the
+	// password was generated with. Rejecting pwned
+	// candidates reduces it by a negligible amount.
+	Entropy float64 `json:"entropy"`
+	// Attempts is the number of candidates that were
+	// generated, including the one returned.
+	Attempts int `json:"attempts"`
+}
+
+// Generator generates passphrases or passwords.
+type Generator struct {
+	// symbols are the words or characters a candidate is
+	// built from and n the number of them in a candidate.
+	symbols    []string
+	n          int
+	separator  string
+	capitalize bool
+	passphrase bool
+
+	search      func(ctx context.Context, password string) (int, error)
+	policy      *policy.Policy
+	maxAttempts int
+}
+
+// Passphrase returns a Generator for diceware style
+// passphrases. By default they are made of DefaultWords
+// words from the EFF large wordlist, separated by
+// DefaultSeparator.
+func Passphrase(opts ...Option) (*Generator, error) {
+	return newGenerator(true, effLargeWordlist[:], DefaultWords, opts)
+}
+
+// Password returns a Generator for random passwords. By
+// default they are DefaultLength characters long, drawn
+// from DefaultCharset.
+func Password(opts ...Option) (*Generator, error) {
+	return newGenerator(false, splitCharset(DefaultCharset), DefaultLength, opts)
+}
+
+func newGenerator(passphrase bool, symbols []string, n int, opts []Option) (*Generator, error) {
+	g := &Generator{
+		symbols:    symbols,
+		n:          n,
+		separator:  DefaultSeparator,
+		passphrase: passphrase,
+
+		maxAttempts: DefaultMaxAttempts,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	g.symbols = dedupe(g.symbols)
+	if len(g.symbols) < 2 {
+		return nil, errors.New("pwned/generate: at least two distinct words or characters are required")
+	}
+
+	if g.n < 1 {
+		return nil, errors.New("pwned/generate: length must be positive")
+	}
+
+	return g, nil
+}
+
+// Entropy returns the entropy, in bits, of the passwords
+// generated by g.
+func (g *Generator) Entropy() float64 {
+	return float64(g.n) * math.Log2(float64(len(g.symbols)))
+}
+
+// Generate returns a new password. Each candidate is
+// searched for in the pwned password list given to
+// WithRanger or WithClient and checked against the policy
+// given to WithPolicy. Rejected candidates are replaced,
+// up to the maximum number of attempts.
+func (g *Generator) Generate(ctx context.Context) (*Result, error) {
+	for attempt := 1; attempt <= g.maxAttempts; attempt++ {
+		password, err := g.candidate()
+		if err != nil {
+			return nil, fmt.Errorf("pwned/generate: failed to read random data: %v", err)
+		}
+
+		ok, err := g.acceptable(ctx, password)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return &Result{
+				Password: password,
+				Entropy:  g.Entropy(),
+				Attempts: attempt,
+			}, nil
+		}
+	}
+
+	return nil, ErrTooManyAttempts
+}
+
+func (g *Generator) candidate() (string, error) {
+	max := big.NewInt(int64(len(g.symbols)))
+
+	parts := make([]string, g.n)
+	for i := range parts {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		parts[i] = g.symbols[idx.Int64()]
+		if g.capitalize {
+			parts[i] = capitalize(parts[i])
+		}
+	}
+
+	if !g.passphrase {
+		return strings.Join(parts, ""), nil
+	}
+
+	return strings.Join(parts, g.separator), nil
+}
+
+func (g *Generator) acceptable(ctx context.Context, password string) (bool, error) {
+	if g.search != nil {
+		count, err := g.search(ctx, password)
+		if err != nil || count > 0 {
+			return false, err
+		}
+	}
+
+	if g.policy != nil {
+		violations, err := g.policy.Check(ctx, password)
+		if err != nil || len(violations) > 0 {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + word[size:]
+}
+
+func splitCharset(charset string) []string {
+	symbols := make([]string, 0, len(charset))
+	for _, r := range charset {
+		symbols = append(symbols, string(r))
+	}
+
+	return symbols
+}
+
+func dedupe(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+// Option allows the behaviour of the Generator to be
+// configured.
+type Option func(*Generator)
+
+// WithWords sets the number of words in a passphrase. It
+// defaults to DefaultWords. It has no effect on passwords.
+func WithWords(n int) Option {
+	return func(g *Generator) {
+		if g.passphrase {
+			g.n = n
+		}
+	}
+}
+
+// WithWordlist sets the words that passphrases are made
+// from. It defaults to the EFF large wordlist. Duplicate
+// words are ignored. It has no effect on passwords.
+func WithWordlist(words []string) Option {
+	return func(g *Generator) {
+		if g.passphrase {
+			g.symbols = words
+		}
+	}
+}
+
+// WithSeparator sets the separator between the words of a
+// passphrase. It defaults to DefaultSeparator.
+func WithSeparator(sep string) Option {
+	return func(g *Generator) {
+		g.separator = sep
+	}
+}
+
+// WithCapitalize capitalises the first letter of each
+// word of a passphrase. It does not add any entropy.
+func WithCapitalize() Option {
+	return func(g *Generator) {
+		if g.passphrase {
+			g.capitalize = true
+		}
+	}
+}
+
+// WithLength sets the length of a password. It defaults to
+// DefaultLength. It has no effect on passphrases.
+func WithLength(n int) Option {
+	return func(g *Generator) {
+		if !g.passphrase {
+			g.n = n
+		}
+	}
+}
+
+// WithCharset sets the characters that passwords are made
+// from. It defaults to DefaultCharset. Duplicate
+// characters are ignored. It has no effect on passphrases.
+func WithCharset(charset string) Option {
+	return func(g *Generator) {
+		if !g.passphrase {
+			g.symbols = splitCharset(charset)
+		}
+	}
+}
+
+// WithRanger rejects candidates that are found in r with
+// pwned.Search.
+func WithRanger(r pwned.Ranger) Option {
+	return func(g *Generator) {
+		g.search = func(ctx context.Context, password string) (int, error) {
+			return pwned.Search(ctx, r, password)
+		}
+	}
+}
+
+// WithClient rejects candidates that are found by
+// c.Search. opts are passed to each call.
+func WithClient(c *pwnedgrpc.Client, opts ...grpc.CallOption) Option {
+	return func(g *Generator) {
+		g.search = func(ctx context.Context, password string) (int, error) {
+			return c.Search(ctx, password, opts...)
+		}
+	}
+}
+
+// WithPolicy rejects candidates that violate p.
+func WithPolicy(p *policy.Policy) Option {
+	return func(g *Generator) {
+		g.policy = p
+	}
+}
+
+// WithMaxAttempts sets the number of candidates that are
+// generated before ErrTooManyAttempts is returned. It
+// defaults to DefaultMaxAttempts.
+func WithMaxAttempts(n int) Option {
+	return func(g *Generator) {
+		g.maxAttempts = n
+	}
+}
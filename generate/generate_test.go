@@ -0,0 +1,116 @@
+package generate
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned/policy"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+func TestPassphrase(t *testing.T) {
+	t.Parallel()
+
+	g, err := Passphrase()
+	require.NoError(t, err)
+
+	res, err := g.Generate(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, strings.Split(res.Password, "-"), DefaultWords, res.Password)
+	assert.InDelta(t, 77.5, res.Entropy, 0.1)
+	assert.Equal(t, 1, res.Attempts)
+
+	g, err = Passphrase(WithWords(4), WithSeparator(" "), WithCapitalize(),
+		WithWordlist([]string{"correct", "horse", "battery", "staple", "horse", ""}))
+	require.NoError(t, err)
+
+	res, err = g.Generate(context.Background())
+	require.NoError(t, err)
+
+	words := strings.Split(res.Password, " ")
+	assert.Len(t, words, 4)
+	for _, word := range words {
+		assert.Contains(t, []string{"Correct", "Horse", "Battery", "Staple"}, word)
+	}
+
+	assert.Equal(t, 8.0, res.Entropy)
+}
+
+func TestPassword(t *testing.T) {
+	t.Parallel()
+
+	g, err := Password()
+	require.NoError(t, err)
+
+	res, err := g.Generate(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, res.Password, DefaultLength)
+	for _, r := range res.Password {
+		assert.Contains(t, DefaultCharset, string(r))
+	}
+
+	assert.InDelta(t, DefaultLength*math.Log2(float64(len(DefaultCharset))), res.Entropy, 1e-9)
+
+	g, err = Password(WithLength(12), WithCharset("0123456789abcdefaaa"), WithWords(2))
+	require.NoError(t, err)
+
+	res, err = g.Generate(context.Background())
+	require.NoError(t, err)
+
+	assert.Len(t, res.Password, 12)
+	assert.Equal(t, 48.0, res.Entropy)
+	assert.Equal(t, -1, strings.IndexFunc(res.Password, func(r rune) bool {
+		return !unicode.Is(unicode.ASCII_Hex_Digit, r)
+	}))
+
+	_, err = Password(WithCharset("aaaa"))
+	assert.Error(t, err)
+
+	_, err = Password(WithLength(0))
+	assert.Error(t, err)
+}
+
+func TestGenerateRejectsPwned(t *testing.T) {
+	t.Parallel()
+
+	g, err := Passphrase(WithWords(1), WithWordlist([]string{"password", "hunter2"}),
+		WithRanger(wordlist.New("password")), WithMaxAttempts(1000))
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		res, err := g.Generate(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", res.Password)
+	}
+
+	g, err = Passphrase(WithWords(1), WithWordlist([]string{"password", "hunter2"}),
+		WithRanger(wordlist.New("password", "hunter2")))
+	require.NoError(t, err)
+
+	_, err = g.Generate(context.Background())
+	assert.Equal(t, ErrTooManyAttempts, err)
+}
+
+func TestGeneratePolicy(t *testing.T) {
+	t.Parallel()
+
+	g, err := Password(WithLength(6), WithPolicy(policy.New()))
+	require.NoError(t, err)
+
+	_, err = g.Generate(context.Background())
+	assert.Equal(t, ErrTooManyAttempts, err)
+
+	g, err = Passphrase(WithPolicy(policy.New(policy.WithMinLength(20))))
+	require.NoError(t, err)
+
+	res, err := g.Generate(context.Background())
+	require.NoError(t, err)
+	assert.True(t, len(res.Password) >= 20)
+}
@@ -6,7 +6,9 @@ require (
 	github.com/golang/protobuf v1.3.1
 	github.com/hydrogen18/memlistener v0.0.0-20141126152155-54553eb933fb
 	github.com/stretchr/testify v1.3.0
-	go4.org v0.0.0-20190313082347-94abd6928b1d
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
 	golang.org/x/net v0.0.0-20190522155817-f3200d17e092
+	golang.org/x/text v0.3.0
 	google.golang.org/grpc v1.21.0
 )
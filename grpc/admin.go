@@ -0,0 +1,384 @@
+package pwnedgrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	pb "go.tmthrgd.dev/pwned/grpc/internal/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Reloader contains an optional method that Ranger's may
+// implement to allow the dataset to be reloaded with the
+// ReloadDataset admin RPC.
+type Reloader interface {
+	pwned.Ranger
+	Reload() error
+}
+
+// Cache contains optional methods that Ranger's with a
+// cache may implement to allow the cache to be purged and
+// monitored with the admin RPCs.
+//
+// Purge removes every entry from the cache and returns the
+// number removed. CacheStats returns the number of cache
+// hits and misses since the Ranger was created.
+type Cache interface {
+	pwned.Ranger
+	Purge() int
+	CacheStats() (hits, misses uint64)
+}
+
+// OptionalCache contains an optional method that Ranger's
+// that implement Cache by forwarding to another Ranger,
+// which may or may not have a cache, may implement.
+// HasCache reports whether there is currently a cache. If
+// it returns false, the dataset is treated as not having a
+// cache.
+type OptionalCache interface {
+	Cache
+	HasCache() bool
+}
+
+// cacheOf returns r as a Cache if it has a cache.
+func cacheOf(r pwned.Ranger) (Cache, bool) {
+	c, ok := r.(Cache)
+	if oc, optional := c.(OptionalCache); optional && !oc.HasCache() {
+		return nil, false
+	}
+
+	return c, ok
+}
+
+// AdminServer represents a pwned.Admin service for a
+// Server. It should be attached to a different grpc.Server
+// and listener from the public pwned.Searcher service.
+//
+// Every request must carry the token in an "authorization"
+// metadata entry of the form "Bearer <token>". The token is
+// sent in plaintext unless the grpc.Server uses TLS, so it
+// should only listen on a loopback address or a unix socket
+// otherwise.
+type AdminServer struct {
+	s     *Server
+	token []byte
+
+	setLogLevel func(level string) error
+}
+
+// NewAdminServer creates an AdminServer for s that
+// requires the given token. token must not be empty.
+func NewAdminServer(s *Server, token string, opts ...AdminOption) *AdminServer {
+	if token == "" {
+		panic("pwned: admin token must not be empty")
+	}
+
+	a := &AdminServer{
+		s:     s,
+		token: []byte(token),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// AdminOption allows the behaviour of the AdminServer to
+// be configured.
+type AdminOption func(*AdminServer)
+
+// WithLogLevelHook sets the function that is called by the
+// SetLogLevel admin RPC. The level is passed as given by
+// the client and fn should return an error if it is not
+// valid. Without it, SetLogLevel is unimplemented.
+func WithLogLevelHook(fn func(level string) error) AdminOption {
+	return func(a *AdminServer) {
+		a.setLogLevel = fn
+	}
+}
+
+type pbAdminServer struct{ *AdminServer }
+
+// Attach registers the pwned.Admin service to the given
+// grpc.Server.
+func (a *AdminServer) Attach(srv *grpc.Server) {
+	pb.RegisterAdminServer(srv, pbAdminServer{a})
+}
+
+func (a *AdminServer) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var ok bool
+	for _, auth := range md.Get("authorization") {
+		const bearer = "Bearer "
+		if !strings.HasPrefix(auth, bearer) {
+			continue
+		}
+
+		// Every value is compared to avoid leaking which
+		// matched through the timing.
+		if subtle.ConstantTimeCompare([]byte(auth[len(bearer):]), a.token) == 1 {
+			ok = true
+		}
+	}
+
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid admin token")
+	}
+
+	return nil
+}
+
+func (a pbAdminServer) ReloadDataset(ctx context.Context, req *pb.ReloadDatasetRequest) (*pb.ReloadDatasetResponse, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	ds, err := a.s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := ds.ranger.(Reloader)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "dataset cannot be reloaded")
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.ReloadDatasetResponse{}, nil
+}
+
+func (a pbAdminServer) PurgeCache(ctx context.Context, req *pb.PurgeCacheRequest) (*pb.PurgeCacheResponse, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	ds, err := a.s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := cacheOf(ds.ranger)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "dataset does not have a cache")
+	}
+
+	return &pb.PurgeCacheResponse{
+		Purged: uint64(c.Purge()),
+	}, nil
+}
+
+func (a pbAdminServer) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetStatsResponse{
+		Methods: make([]*pb.MethodStats, 0, len(searcherMethods)),
+	}
+	for _, method := range searcherMethods {
+		c := a.s.methods[method]
+		resp.Methods = append(resp.Methods, &pb.MethodStats{
+			Method:   method,
+			Requests: atomic.LoadUint64(&c.requests),
+			Errors:   atomic.LoadUint64(&c.errors),
+		})
+	}
+
+	names := make([]string, 0, len(a.s.datasets))
+	for name := range a.s.datasets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	resp.Datasets = make([]*pb.DatasetStats, 0, len(names))
+	for _, name := range names {
+		ds := a.s.datasets[name]
+
+		stats := &pb.DatasetStats{
+			Name:             name,
+			UpstreamRequests: atomic.LoadUint64(&ds.upstream.requests),
+			UpstreamErrors:   atomic.LoadUint64(&ds.upstream.errors),
+			UpstreamLatency:  atomic.LoadUint64(&ds.upstream.nanos),
+		}
+
+		if c, ok := cacheOf(ds.ranger); ok {
+			stats.Cache = true
+			stats.CacheHits, stats.CacheMisses = c.CacheStats()
+		}
+
+		resp.Datasets = append(resp.Datasets, stats)
+	}
+
+	return resp, nil
+}
+
+func (a pbAdminServer) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	if a.setLogLevel == nil {
+		return nil, status.Error(codes.Unimplemented, "log level cannot be changed")
+	}
+
+	if err := a.setLogLevel(req.Level); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &pb.SetLogLevelResponse{}, nil
+}
+
+// AdminClient wraps a grpc.ClientConn for use with the
+// pwned.Admin service.
+type AdminClient struct {
+	cc    *grpc.ClientConn
+	pc    pb.AdminClient
+	token string
+}
+
+// NewAdminClient creates an AdminClient from a given
+// grpc.ClientConn that authenticates with token.
+func NewAdminClient(cc *grpc.ClientConn, token string) *AdminClient {
+	return &AdminClient{
+		cc:    cc,
+		pc:    pb.NewAdminClient(cc),
+		token: token,
+	}
+}
+
+// Close calls Close on the underlying grpc.ClientConn.
+func (c *AdminClient) Close() error {
+	return c.cc.Close()
+}
+
+func (c *AdminClient) authorize(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.token)
+}
+
+// ReloadDataset reloads the named dataset, or the default
+// dataset if name is empty.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *AdminClient) ReloadDataset(ctx context.Context, name string, opts ...grpc.CallOption) error {
+	_, err := c.pc.ReloadDataset(c.authorize(ctx), &pb.ReloadDatasetRequest{
+		Dataset: name,
+	}, opts...)
+	return err
+}
+
+// PurgeCache empties the cache of the named dataset, or
+// the default dataset if name is empty, and returns the
+// number of entries removed.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *AdminClient) PurgeCache(ctx context.Context, name string, opts ...grpc.CallOption) (purged int, err error) {
+	resp, err := c.pc.PurgeCache(c.authorize(ctx), &pb.PurgeCacheRequest{
+		Dataset: name,
+	}, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resp.Purged), nil
+}
+
+// Stats contains the statistics reported by the server.
+type Stats struct {
+	Methods  []MethodStats
+	Datasets []DatasetStats
+}
+
+// MethodStats contains the statistics for a single
+// pwned.Searcher RPC.
+type MethodStats struct {
+	// Method is the name of the RPC, such as "Lookup".
+	Method string
+
+	Requests uint64
+	Errors   uint64
+}
+
+// DatasetStats contains the statistics for a single
+// dataset.
+type DatasetStats struct {
+	// Name is empty for the default dataset.
+	Name string
+
+	// UpstreamRequests is the number of calls made to the
+	// dataset's source and UpstreamLatency is the total
+	// time spent in those calls.
+	UpstreamRequests uint64
+	UpstreamErrors   uint64
+	UpstreamLatency  time.Duration
+
+	// Cache is true if the dataset has a cache, in which
+	// case CacheHits and CacheMisses are set.
+	Cache       bool
+	CacheHits   uint64
+	CacheMisses uint64
+}
+
+// Stats returns the server's request and cache statistics.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *AdminClient) Stats(ctx context.Context, opts ...grpc.CallOption) (*Stats, error) {
+	resp, err := c.pc.GetStats(c.authorize(ctx), &pb.GetStatsRequest{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{
+		Methods:  make([]MethodStats, len(resp.Methods)),
+		Datasets: make([]DatasetStats, len(resp.Datasets)),
+	}
+	for i, m := range resp.Methods {
+		stats.Methods[i] = MethodStats{
+			Method:   m.Method,
+			Requests: m.Requests,
+			Errors:   m.Errors,
+		}
+	}
+	for i, ds := range resp.Datasets {
+		stats.Datasets[i] = DatasetStats{
+			Name:             ds.Name,
+			UpstreamRequests: ds.UpstreamRequests,
+			UpstreamErrors:   ds.UpstreamErrors,
+			UpstreamLatency:  time.Duration(ds.UpstreamLatency),
+			Cache:            ds.Cache,
+			CacheHits:        ds.CacheHits,
+			CacheMisses:      ds.CacheMisses,
+		}
+	}
+
+	return stats, nil
+}
+
+// SetLogLevel changes the server's log level. The levels
+// that are accepted are up to the server.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *AdminClient) SetLogLevel(ctx context.Context, level string, opts ...grpc.CallOption) error {
+	_, err := c.pc.SetLogLevel(c.authorize(ctx), &pb.SetLogLevelRequest{
+		Level: level,
+	}, opts...)
+	return err
+}
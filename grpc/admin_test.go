@@ -0,0 +1,99 @@
+package pwnedgrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned/internal/test"
+	"go.tmthrgd.dev/pwned/wordlist"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdmin(t *testing.T) {
+	t.Parallel()
+
+	c := &cache{Ranger: wordlist.New("password")}
+
+	var level string
+	srv := NewServer(c, WithDataset("hibp", gateway{}),
+		WithDataset("uncached", &optionalCache{cache: c}))
+	admin := NewAdminServer(srv, "secret", WithLogLevelHook(func(l string) error {
+		level = l
+		return nil
+	}))
+
+	sc, stop := test.TestingClient(srv.Attach)
+	defer stop()
+
+	ac, stop := test.TestingClient(admin.Attach)
+	defer stop()
+
+	_, err := NewClient(sc).Search(context.Background(), "password")
+	require.NoError(t, err)
+
+	cc := NewAdminClient(ac, "secret")
+
+	require.NoError(t, cc.ReloadDataset(context.Background(), ""))
+	assert.Equal(t, 1, c.reloads)
+
+	err = cc.ReloadDataset(context.Background(), "hibp")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	purged, err := cc.PurgeCache(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged)
+
+	_, err = cc.PurgeCache(context.Background(), "uncached")
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	stats, err := cc.Stats(context.Background())
+	require.NoError(t, err)
+	require.Len(t, stats.Methods, 4)
+	assert.Equal(t, MethodStats{Method: "Range", Requests: 1}, stats.Methods[1])
+	require.Len(t, stats.Datasets, 3)
+	assert.Equal(t, uint64(1), stats.Datasets[0].UpstreamRequests)
+	assert.True(t, stats.Datasets[0].Cache)
+	assert.Equal(t, uint64(5), stats.Datasets[0].CacheHits)
+	assert.Equal(t, uint64(2), stats.Datasets[0].CacheMisses)
+	assert.False(t, stats.Datasets[1].Cache)
+	assert.False(t, stats.Datasets[2].Cache)
+
+	require.NoError(t, cc.SetLogLevel(context.Background(), "debug"))
+	assert.Equal(t, "debug", level)
+
+	err = NewAdminClient(ac, "wrong").ReloadDataset(context.Background(), "")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = NewAdminClient(ac, "").Stats(context.Background())
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+type cache struct {
+	*wordlist.Ranger
+	reloads int
+}
+
+func (c *cache) Reload() error {
+	c.reloads++
+	return nil
+}
+
+func (c *cache) Purge() int {
+	return 3
+}
+
+func (c *cache) CacheStats() (hits, misses uint64) {
+	return 5, 2
+}
+
+// optionalCache implements Cache without having a cache.
+type optionalCache struct {
+	*cache
+}
+
+func (*optionalCache) HasCache() bool {
+	return false
+}
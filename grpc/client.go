@@ -4,11 +4,15 @@ import (
 	"context"
 	"crypto/sha1"
 	"errors"
+	"time"
 
 	"go.tmthrgd.dev/pwned"
 	pb "go.tmthrgd.dev/pwned/grpc/internal/proto"
+	"go.tmthrgd.dev/pwned/normalize"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
 )
 
 // Client wraps a grpc.ClientConn for use with the
@@ -43,11 +47,21 @@ func (c *Client) Close() error {
 // Lookup reveals the password to the server so should be
 // used with caution. It has the sole benefit of reducing
 // network data transfers.
+//
+// With the Normalize call option, each form of the password
+// is looked up in turn.
 func (c *Client) Lookup(ctx context.Context, password string, opts ...grpc.CallOption) (count int, err error) {
+	return c.forms(password, opts, func(password string) (int, error) {
+		return c.lookup(ctx, password, opts)
+	})
+}
+
+func (c *Client) lookup(ctx context.Context, password string, opts []grpc.CallOption) (count int, err error) {
 	digest := sha1.Sum([]byte(password))
 
 	resp, err := c.pc.Lookup(ctx, &pb.LookupRequest{
-		Digest: digest[:],
+		Digest:  digest[:],
+		Dataset: datasetName(opts),
 	}, disableCompression(opts)...)
 	if err != nil {
 		return 0, err
@@ -68,22 +82,287 @@ func (c *Client) Lookup(ctx context.Context, password string, opts ...grpc.CallO
 // Search relies on k-anonymity and does not reveal the
 // password to the server. It requires the transfer of
 // several KiB of data, but mitigates leaks of the password.
+//
+// If the server only stores truncated hashes, Search will
+// only compare the truncated hashes and may return false
+// positives at the rate chosen by the server.
+//
+// With the Normalize call option, each form of the password
+// is searched for in turn.
 func (c *Client) Search(ctx context.Context, password string, opts ...grpc.CallOption) (count int, err error) {
+	return c.forms(password, opts, func(password string) (int, error) {
+		return c.search(ctx, password, opts)
+	})
+}
+
+func (c *Client) search(ctx context.Context, password string, opts []grpc.CallOption) (count int, err error) {
 	digest := sha1.Sum([]byte(password))
 	prefix, suffix := pwned.SplitDigest(digest)
 
+	results, bits, err := c.Range(ctx, prefix, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	if bits != 0 {
+		return pwned.SearchTruncatedSet(results, suffix, bits), nil
+	}
+
+	return pwned.SearchSet(results, suffix), nil
+}
+
+// Range returns the raw results of a range query for the
+// given prefix, in the format produced by
+// pwned.AppendResult. If truncatedBits is non-zero, the
+// hashes have been truncated to that many bits, as
+// produced by pwned.AppendTruncatedResult.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+//
+// Range is useful for checking many passwords that share
+// a prefix with a single request. Search should be used
+// otherwise.
+func (c *Client) Range(ctx context.Context, prefix string, opts ...grpc.CallOption) (results []byte, truncatedBits int, err error) {
 	resp, err := c.pc.Range(ctx, &pb.RangeRequest{
-		Prefix: prefix,
+		Prefix:  prefix,
+		Dataset: datasetName(opts),
 	}, opts...)
 	if err != nil {
-		return 0, err
+		return nil, 0, err
+	}
+
+	bits, err := checkTruncatedBits(resp.TruncatedBits)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if bits == 0 {
+		if len(resp.Results)%(pwned.SuffixSize+1) != 0 {
+			return nil, 0, errors.New("pwned: invalid result set returned")
+		}
+
+		return resp.Results, 0, nil
+	}
+
+	if len(resp.Results)%(pwned.TruncatedSuffixSize(bits)+1) != 0 {
+		return nil, 0, errors.New("pwned: invalid result set returned")
+	}
+
+	return resp.Results, bits, nil
+}
+
+type clientSource struct {
+	c    *Client
+	opts []grpc.CallOption
+}
+
+// ClientSource returns a pwned.Source that queries a pwned
+// server through c. opts are passed to each call.
+func ClientSource(c *Client, opts ...grpc.CallOption) pwned.Source {
+	return clientSource{c, opts}
+}
+
+func (s clientSource) Range(ctx context.Context, prefix string) ([]byte, int, error) {
+	return s.c.Range(ctx, prefix, s.opts...)
+}
+
+// Dataset describes a dataset served by the server.
+type Dataset struct {
+	// Name is empty for the default dataset.
+	Name string
+
+	// Lookup is true if the dataset supports server side
+	// lookups. If it is false, Lookup will still work but
+	// the server will perform the range search itself.
+	Lookup bool
+
+	// TruncatedBits is the number of bits the hashes in
+	// the dataset have been truncated to, or zero if they
+	// have not been truncated.
+	TruncatedBits int
+
+	// Info describes the dataset. It is nil if the server
+	// does not provide it, or failed to get it.
+	Info *pwned.Info
+}
+
+// ListDatasets returns the datasets served by the server.
+//
+// opts can be used to provide grpc.CallOption's to the
+// underlying connection.
+func (c *Client) ListDatasets(ctx context.Context, opts ...grpc.CallOption) ([]Dataset, error) {
+	resp, err := c.pc.ListDatasets(ctx, &pb.ListDatasetsRequest{}, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(resp.Results)%(pwned.SuffixSize+1) != 0 {
-		return 0, errors.New("pwned: invalid result set returned")
+	datasets := make([]Dataset, len(resp.Datasets))
+	for i, ds := range resp.Datasets {
+		bits, err := checkTruncatedBits(ds.TruncatedBits)
+		if err != nil {
+			return nil, err
+		}
+
+		datasets[i] = Dataset{
+			Name:          ds.Name,
+			Lookup:        ds.Lookup,
+			TruncatedBits: bits,
+			Info:          fromPBInfo(ds.Info),
+		}
 	}
 
-	return pwned.SearchSet(resp.Results, suffix), nil
+	return datasets, nil
+}
+
+// checkTruncatedBits checks the truncated_bits sent by the
+// server, which pwned.TruncatedSuffixSize would otherwise
+// panic on.
+func checkTruncatedBits(bits uint32) (int, error) {
+	if bits != 0 && (bits%8 != 0 || bits < 32 || bits > 8*sha1.Size) {
+		return 0, status.Error(codes.Internal, "pwned: invalid truncated_bits returned")
+	}
+
+	return int(bits), nil
+}
+
+// Info describes the dataset searched by Lookup and
+// Search. The UseDataset call option selects which
+// dataset is described.
+//
+// It returns an error with the codes.Unimplemented code
+// if the server does not provide information about the
+// dataset.
+func (c *Client) Info(ctx context.Context, opts ...grpc.CallOption) (*pwned.Info, error) {
+	resp, err := c.pc.Info(ctx, &pb.InfoRequest{
+		Dataset: datasetName(opts),
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Info == nil {
+		return nil, errors.New("pwned: no dataset info returned")
+	}
+
+	return fromPBInfo(resp.Info), nil
+}
+
+func fromPBInfo(info *pb.DatasetInfo) *pwned.Info {
+	if info == nil {
+		return nil
+	}
+
+	var built time.Time
+	if info.Built != 0 {
+		built = time.Unix(info.Built, 0)
+	}
+
+	return &pwned.Info{
+		Version:   info.Version,
+		Entries:   info.Entries,
+		Prefixes:  info.Prefixes,
+		Algorithm: info.Algorithm,
+		Encoding:  info.Encoding,
+		Built:     built,
+	}
+}
+
+// datasetOption is a grpc.CallOption that selects the
+// dataset to search. It has no effect on the underlying
+// connection.
+type datasetOption struct {
+	grpc.EmptyCallOption
+	name string
+}
+
+// UseDataset returns a grpc.CallOption that can be passed
+// to Lookup or Search to select a named dataset. Without
+// it, the server's default dataset is searched.
+func UseDataset(name string) grpc.CallOption {
+	return datasetOption{name: name}
+}
+
+func datasetName(opts []grpc.CallOption) (name string) {
+	for _, opt := range opts {
+		if ds, ok := opt.(datasetOption); ok {
+			name = ds.name
+		}
+	}
+
+	return name
+}
+
+// forms calls fn with each form of password, as selected by
+// the Normalize call option, until one is found. It reports
+// the form that was found to any MatchedForm call option.
+func (c *Client) forms(password string, opts []grpc.CallOption, fn func(password string) (int, error)) (int, error) {
+	candidates := []normalize.Candidate{{Form: normalize.Original, Password: password}}
+	for _, opt := range opts {
+		if n, ok := opt.(normalizeOption); ok {
+			var err error
+			if candidates, err = normalize.Forms(password, n.invalid); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	matched, count := normalize.None, 0
+	for _, cand := range candidates {
+		n, err := fn(cand.Password)
+		if err != nil {
+			return 0, err
+		}
+
+		if n > 0 {
+			matched, count = cand.Form, n
+			break
+		}
+	}
+
+	for _, opt := range opts {
+		if m, ok := opt.(matchedFormOption); ok {
+			*m.form = matched
+		}
+	}
+
+	return count, nil
+}
+
+// normalizeOption is a grpc.CallOption that normalises
+// passwords before they are hashed. It has no effect on the
+// underlying connection.
+type normalizeOption struct {
+	grpc.EmptyCallOption
+	invalid normalize.InvalidUTF8
+}
+
+// Normalize returns a grpc.CallOption that can be passed to
+// Lookup or Search to check the NFKC form of the password,
+// and then the original form if it differs, as recommended
+// by NIST SP 800-63B. invalid chooses what happens if the
+// password is not valid UTF-8, see normalize.Forms.
+//
+// Without it, the password is hashed as given.
+func Normalize(invalid normalize.InvalidUTF8) grpc.CallOption {
+	return normalizeOption{invalid: invalid}
+}
+
+// matchedFormOption is a grpc.CallOption that reports the
+// form of the password that was found. It has no effect on
+// the underlying connection.
+type matchedFormOption struct {
+	grpc.EmptyCallOption
+	form *normalize.Form
+}
+
+// MatchedForm returns a grpc.CallOption that can be passed
+// to Lookup or Search to learn which form of the password
+// was found. form is set to normalize.None if the password
+// was not found. It is set when the call completes without
+// error.
+func MatchedForm(form *normalize.Form) grpc.CallOption {
+	return matchedFormOption{form: form}
 }
 
 // disableCompression does what it says on the tin. It's
@@ -3,12 +3,23 @@ package pwnedgrpc
 import (
 	"context"
 	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.tmthrgd.dev/pwned"
+	pb "go.tmthrgd.dev/pwned/grpc/internal/proto"
 	"go.tmthrgd.dev/pwned/internal/test"
+	"go.tmthrgd.dev/pwned/normalize"
+	"go.tmthrgd.dev/pwned/passwords"
+	"go.tmthrgd.dev/pwned/store/truncated"
+	"go.tmthrgd.dev/pwned/wordlist"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ranger map[string][]byte
@@ -73,3 +84,136 @@ func TestSearchNotPresent(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 0, count)
 }
+
+func TestSearchWordlist(t *testing.T) {
+	t.Parallel()
+
+	c, stop := test.TestingClient(NewServer(wordlist.New("password", "password", "P@ssw0rd")).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	count, err := cc.Search(context.Background(), "password")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = cc.Lookup(context.Background(), "P@ssw0rd")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = cc.Search(context.Background(), "correct horse battery staple")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestSearchNormalize(t *testing.T) {
+	t.Parallel()
+
+	search := wordlist.New("password", "caf\u00e9", "\ufb01sh")
+
+	c, stop := test.TestingClient(NewServer(search).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	for _, tc := range []struct {
+		password string
+		count    int
+		form     normalize.Form
+		lookup   bool
+	}{
+		{"password", 1, normalize.NFKC, false},
+		{"ｐａｓｓｗｏｒｄ", 1, normalize.NFKC, false},
+		{"cafe\u0301", 1, normalize.NFKC, true},
+		{"\ufb01sh", 1, normalize.Original, false},
+		{"\ufb01sh", 1, normalize.Original, true},
+		{"correct horse battery staple", 0, normalize.None, false},
+	} {
+		var form normalize.Form
+		opts := []grpc.CallOption{Normalize(normalize.Reject), MatchedForm(&form)}
+
+		fn := cc.Search
+		if tc.lookup {
+			fn = cc.Lookup
+		}
+
+		count, err := fn(context.Background(), tc.password, opts...)
+		require.NoError(t, err, tc.password)
+		assert.Equal(t, tc.count, count, tc.password)
+		assert.Equal(t, tc.form, form, tc.password)
+	}
+
+	form := normalize.NFKC
+	count, err := cc.Search(context.Background(), "ｐａｓｓｗｏｒｄ", MatchedForm(&form))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, normalize.None, form)
+
+	count, err = cc.Search(context.Background(), "password", MatchedForm(&form))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, normalize.Original, form)
+
+	_, err = cc.Search(context.Background(), "pass\xffword", Normalize(normalize.Reject))
+	assert.Equal(t, normalize.ErrInvalidUTF8, err)
+}
+
+func TestSearchTruncated(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for _, password := range []string{"password", "P@ssw0rd", "lauragpe"} {
+		digest := sha1.Sum([]byte(password))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(digest[:]))+":8")
+	}
+	sort.Strings(lines)
+
+	store, err := truncated.Build(passwords.NewDatasetReader(strings.NewReader(strings.Join(lines, "\n"))), 64)
+	require.NoError(t, err)
+
+	c, stop := test.TestingClient(NewServer(store).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	count, err := cc.Search(context.Background(), "password")
+	require.NoError(t, err)
+	assert.Equal(t, 8, count)
+
+	count, err = cc.Search(context.Background(), "correct horse battery staple")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// badTruncatedServer returns an invalid truncated_bits.
+type badTruncatedServer struct{ pb.SearcherServer }
+
+func (badTruncatedServer) Range(ctx context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	return &pb.RangeResponse{Results: make([]byte, 19), TruncatedBits: 12}, nil
+}
+
+func (badTruncatedServer) ListDatasets(ctx context.Context, req *pb.ListDatasetsRequest) (*pb.ListDatasetsResponse, error) {
+	return &pb.ListDatasetsResponse{
+		Datasets: []*pb.Dataset{{TruncatedBits: 200}},
+	}, nil
+}
+
+func TestSearchInvalidTruncatedBits(t *testing.T) {
+	t.Parallel()
+
+	c, stop := test.TestingClient(func(srv *grpc.Server) {
+		pb.RegisterSearcherServer(srv, badTruncatedServer{})
+	})
+	defer stop()
+
+	cc := NewClient(c)
+
+	_, err := cc.Search(context.Background(), "password")
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, _, err = cc.Range(context.Background(), "5baa6")
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = cc.ListDatasets(context.Background())
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
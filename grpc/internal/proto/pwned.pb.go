@@ -12,6 +12,22 @@ It has these top-level messages:
 	LookupResponse
 	RangeRequest
 	RangeResponse
+	ListDatasetsRequest
+	ListDatasetsResponse
+	Dataset
+	InfoRequest
+	InfoResponse
+	DatasetInfo
+	ReloadDatasetRequest
+	ReloadDatasetResponse
+	PurgeCacheRequest
+	PurgeCacheResponse
+	GetStatsRequest
+	GetStatsResponse
+	MethodStats
+	DatasetStats
+	SetLogLevelRequest
+	SetLogLevelResponse
 */
 package proto
 
@@ -37,6 +53,9 @@ const _ = proto1.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type LookupRequest struct {
 	Digest []byte `protobuf:"bytes,1,opt,name=digest,proto3" json:"digest,omitempty"`
+	// Dataset is the name of the dataset to search. If
+	// empty, the default dataset is searched.
+	Dataset string `protobuf:"bytes,2,opt,name=dataset" json:"dataset,omitempty"`
 }
 
 func (m *LookupRequest) Reset()                    { *m = LookupRequest{} }
@@ -51,6 +70,13 @@ func (m *LookupRequest) GetDigest() []byte {
 	return nil
 }
 
+func (m *LookupRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
 type LookupResponse struct {
 	Count uint32 `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
 }
@@ -70,6 +96,9 @@ func (m *LookupResponse) GetCount() uint32 {
 type RangeRequest struct {
 	// Prefix is hex encoded.
 	Prefix string `protobuf:"bytes,1,opt,name=prefix" json:"prefix,omitempty"`
+	// Dataset is the name of the dataset to search. If
+	// empty, the default dataset is searched.
+	Dataset string `protobuf:"bytes,2,opt,name=dataset" json:"dataset,omitempty"`
 }
 
 func (m *RangeRequest) Reset()                    { *m = RangeRequest{} }
@@ -84,6 +113,13 @@ func (m *RangeRequest) GetPrefix() string {
 	return ""
 }
 
+func (m *RangeRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
 type RangeResponse struct {
 	// The results format is:
 	//  suffix0 || logcnt0 ||
@@ -94,6 +130,12 @@ type RangeResponse struct {
 	//
 	// It's length is 18*N + N.
 	Results []byte `protobuf:"bytes,1,opt,name=results,proto3" json:"results,omitempty"`
+	// If non-zero, the hashes in results have been
+	// truncated to this many bits. Each suffix is then
+	// truncated_bits/8 - 2 bytes long and clients must
+	// compare only the equivalent leading bytes of their
+	// own suffix.
+	TruncatedBits uint32 `protobuf:"varint,2,opt,name=truncated_bits,json=truncatedBits" json:"truncated_bits,omitempty"`
 }
 
 func (m *RangeResponse) Reset()                    { *m = RangeResponse{} }
@@ -108,11 +150,424 @@ func (m *RangeResponse) GetResults() []byte {
 	return nil
 }
 
+func (m *RangeResponse) GetTruncatedBits() uint32 {
+	if m != nil {
+		return m.TruncatedBits
+	}
+	return 0
+}
+
+type ListDatasetsRequest struct {
+}
+
+func (m *ListDatasetsRequest) Reset()                    { *m = ListDatasetsRequest{} }
+func (m *ListDatasetsRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ListDatasetsRequest) ProtoMessage()               {}
+func (*ListDatasetsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type ListDatasetsResponse struct {
+	Datasets []*Dataset `protobuf:"bytes,1,rep,name=datasets" json:"datasets,omitempty"`
+}
+
+func (m *ListDatasetsResponse) Reset()                    { *m = ListDatasetsResponse{} }
+func (m *ListDatasetsResponse) String() string            { return proto1.CompactTextString(m) }
+func (*ListDatasetsResponse) ProtoMessage()               {}
+func (*ListDatasetsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *ListDatasetsResponse) GetDatasets() []*Dataset {
+	if m != nil {
+		return m.Datasets
+	}
+	return nil
+}
+
+type Dataset struct {
+	// Name is empty for the default dataset.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Lookup is true if the dataset supports server side
+	// lookups rather than only range queries.
+	Lookup bool `protobuf:"varint,2,opt,name=lookup" json:"lookup,omitempty"`
+	// See RangeResponse.truncated_bits.
+	TruncatedBits uint32 `protobuf:"varint,3,opt,name=truncated_bits,json=truncatedBits" json:"truncated_bits,omitempty"`
+	// Info is not set if the dataset does not provide it.
+	Info *DatasetInfo `protobuf:"bytes,4,opt,name=info" json:"info,omitempty"`
+}
+
+func (m *Dataset) Reset()                    { *m = Dataset{} }
+func (m *Dataset) String() string            { return proto1.CompactTextString(m) }
+func (*Dataset) ProtoMessage()               {}
+func (*Dataset) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Dataset) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Dataset) GetLookup() bool {
+	if m != nil {
+		return m.Lookup
+	}
+	return false
+}
+
+func (m *Dataset) GetTruncatedBits() uint32 {
+	if m != nil {
+		return m.TruncatedBits
+	}
+	return 0
+}
+
+func (m *Dataset) GetInfo() *DatasetInfo {
+	if m != nil {
+		return m.Info
+	}
+	return nil
+}
+
+type InfoRequest struct {
+	// Dataset is the name of the dataset to describe. If
+	// empty, the default dataset is described.
+	Dataset string `protobuf:"bytes,1,opt,name=dataset" json:"dataset,omitempty"`
+}
+
+func (m *InfoRequest) Reset()                    { *m = InfoRequest{} }
+func (m *InfoRequest) String() string            { return proto1.CompactTextString(m) }
+func (*InfoRequest) ProtoMessage()               {}
+func (*InfoRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *InfoRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type InfoResponse struct {
+	Info *DatasetInfo `protobuf:"bytes,1,opt,name=info" json:"info,omitempty"`
+}
+
+func (m *InfoResponse) Reset()                    { *m = InfoResponse{} }
+func (m *InfoResponse) String() string            { return proto1.CompactTextString(m) }
+func (*InfoResponse) ProtoMessage()               {}
+func (*InfoResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *InfoResponse) GetInfo() *DatasetInfo {
+	if m != nil {
+		return m.Info
+	}
+	return nil
+}
+
+type DatasetInfo struct {
+	// Version identifies the dataset, such as "v8" or the
+	// date a mirror was crawled.
+	Version string `protobuf:"bytes,1,opt,name=version" json:"version,omitempty"`
+	// Entries and prefixes are zero if unknown.
+	Entries  uint64 `protobuf:"varint,2,opt,name=entries" json:"entries,omitempty"`
+	Prefixes uint64 `protobuf:"varint,3,opt,name=prefixes" json:"prefixes,omitempty"`
+	// Algorithm is the hash algorithm, such as "SHA1".
+	Algorithm string `protobuf:"bytes,4,opt,name=algorithm" json:"algorithm,omitempty"`
+	// Encoding describes how the dataset is stored.
+	Encoding string `protobuf:"bytes,5,opt,name=encoding" json:"encoding,omitempty"`
+	// Built is a unix timestamp in seconds, or zero if
+	// unknown.
+	Built int64 `protobuf:"varint,6,opt,name=built" json:"built,omitempty"`
+}
+
+func (m *DatasetInfo) Reset()                    { *m = DatasetInfo{} }
+func (m *DatasetInfo) String() string            { return proto1.CompactTextString(m) }
+func (*DatasetInfo) ProtoMessage()               {}
+func (*DatasetInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *DatasetInfo) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetEntries() uint64 {
+	if m != nil {
+		return m.Entries
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetPrefixes() uint64 {
+	if m != nil {
+		return m.Prefixes
+	}
+	return 0
+}
+
+func (m *DatasetInfo) GetAlgorithm() string {
+	if m != nil {
+		return m.Algorithm
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetEncoding() string {
+	if m != nil {
+		return m.Encoding
+	}
+	return ""
+}
+
+func (m *DatasetInfo) GetBuilt() int64 {
+	if m != nil {
+		return m.Built
+	}
+	return 0
+}
+
+type ReloadDatasetRequest struct {
+	// Dataset is the name of the dataset to reload. If
+	// empty, the default dataset is reloaded.
+	Dataset string `protobuf:"bytes,1,opt,name=dataset" json:"dataset,omitempty"`
+}
+
+func (m *ReloadDatasetRequest) Reset()                    { *m = ReloadDatasetRequest{} }
+func (m *ReloadDatasetRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ReloadDatasetRequest) ProtoMessage()               {}
+func (*ReloadDatasetRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *ReloadDatasetRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type ReloadDatasetResponse struct {
+}
+
+func (m *ReloadDatasetResponse) Reset()                    { *m = ReloadDatasetResponse{} }
+func (m *ReloadDatasetResponse) String() string            { return proto1.CompactTextString(m) }
+func (*ReloadDatasetResponse) ProtoMessage()               {}
+func (*ReloadDatasetResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type PurgeCacheRequest struct {
+	// Dataset is the name of the dataset whose cache is
+	// purged. If empty, the default dataset is purged.
+	Dataset string `protobuf:"bytes,1,opt,name=dataset" json:"dataset,omitempty"`
+}
+
+func (m *PurgeCacheRequest) Reset()                    { *m = PurgeCacheRequest{} }
+func (m *PurgeCacheRequest) String() string            { return proto1.CompactTextString(m) }
+func (*PurgeCacheRequest) ProtoMessage()               {}
+func (*PurgeCacheRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *PurgeCacheRequest) GetDataset() string {
+	if m != nil {
+		return m.Dataset
+	}
+	return ""
+}
+
+type PurgeCacheResponse struct {
+	// Purged is the number of cache entries removed.
+	Purged uint64 `protobuf:"varint,1,opt,name=purged" json:"purged,omitempty"`
+}
+
+func (m *PurgeCacheResponse) Reset()                    { *m = PurgeCacheResponse{} }
+func (m *PurgeCacheResponse) String() string            { return proto1.CompactTextString(m) }
+func (*PurgeCacheResponse) ProtoMessage()               {}
+func (*PurgeCacheResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *PurgeCacheResponse) GetPurged() uint64 {
+	if m != nil {
+		return m.Purged
+	}
+	return 0
+}
+
+type GetStatsRequest struct {
+}
+
+func (m *GetStatsRequest) Reset()                    { *m = GetStatsRequest{} }
+func (m *GetStatsRequest) String() string            { return proto1.CompactTextString(m) }
+func (*GetStatsRequest) ProtoMessage()               {}
+func (*GetStatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+type GetStatsResponse struct {
+	Methods  []*MethodStats  `protobuf:"bytes,1,rep,name=methods" json:"methods,omitempty"`
+	Datasets []*DatasetStats `protobuf:"bytes,2,rep,name=datasets" json:"datasets,omitempty"`
+}
+
+func (m *GetStatsResponse) Reset()                    { *m = GetStatsResponse{} }
+func (m *GetStatsResponse) String() string            { return proto1.CompactTextString(m) }
+func (*GetStatsResponse) ProtoMessage()               {}
+func (*GetStatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *GetStatsResponse) GetMethods() []*MethodStats {
+	if m != nil {
+		return m.Methods
+	}
+	return nil
+}
+
+func (m *GetStatsResponse) GetDatasets() []*DatasetStats {
+	if m != nil {
+		return m.Datasets
+	}
+	return nil
+}
+
+type MethodStats struct {
+	// Method is the name of a Searcher RPC, such as
+	// "Lookup".
+	Method   string `protobuf:"bytes,1,opt,name=method" json:"method,omitempty"`
+	Requests uint64 `protobuf:"varint,2,opt,name=requests" json:"requests,omitempty"`
+	Errors   uint64 `protobuf:"varint,3,opt,name=errors" json:"errors,omitempty"`
+}
+
+func (m *MethodStats) Reset()                    { *m = MethodStats{} }
+func (m *MethodStats) String() string            { return proto1.CompactTextString(m) }
+func (*MethodStats) ProtoMessage()               {}
+func (*MethodStats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *MethodStats) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *MethodStats) GetRequests() uint64 {
+	if m != nil {
+		return m.Requests
+	}
+	return 0
+}
+
+func (m *MethodStats) GetErrors() uint64 {
+	if m != nil {
+		return m.Errors
+	}
+	return 0
+}
+
+type DatasetStats struct {
+	// Name is empty for the default dataset.
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// Upstream requests are the calls made to the
+	// dataset's source. Latency is the total time spent
+	// in those calls in nanoseconds.
+	UpstreamRequests uint64 `protobuf:"varint,2,opt,name=upstream_requests,json=upstreamRequests" json:"upstream_requests,omitempty"`
+	UpstreamErrors   uint64 `protobuf:"varint,3,opt,name=upstream_errors,json=upstreamErrors" json:"upstream_errors,omitempty"`
+	UpstreamLatency  uint64 `protobuf:"varint,4,opt,name=upstream_latency,json=upstreamLatency" json:"upstream_latency,omitempty"`
+	// Cache is true if the dataset has a cache, in which
+	// case cache_hits and cache_misses are set.
+	Cache       bool   `protobuf:"varint,5,opt,name=cache" json:"cache,omitempty"`
+	CacheHits   uint64 `protobuf:"varint,6,opt,name=cache_hits,json=cacheHits" json:"cache_hits,omitempty"`
+	CacheMisses uint64 `protobuf:"varint,7,opt,name=cache_misses,json=cacheMisses" json:"cache_misses,omitempty"`
+}
+
+func (m *DatasetStats) Reset()                    { *m = DatasetStats{} }
+func (m *DatasetStats) String() string            { return proto1.CompactTextString(m) }
+func (*DatasetStats) ProtoMessage()               {}
+func (*DatasetStats) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *DatasetStats) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DatasetStats) GetUpstreamRequests() uint64 {
+	if m != nil {
+		return m.UpstreamRequests
+	}
+	return 0
+}
+
+func (m *DatasetStats) GetUpstreamErrors() uint64 {
+	if m != nil {
+		return m.UpstreamErrors
+	}
+	return 0
+}
+
+func (m *DatasetStats) GetUpstreamLatency() uint64 {
+	if m != nil {
+		return m.UpstreamLatency
+	}
+	return 0
+}
+
+func (m *DatasetStats) GetCache() bool {
+	if m != nil {
+		return m.Cache
+	}
+	return false
+}
+
+func (m *DatasetStats) GetCacheHits() uint64 {
+	if m != nil {
+		return m.CacheHits
+	}
+	return 0
+}
+
+func (m *DatasetStats) GetCacheMisses() uint64 {
+	if m != nil {
+		return m.CacheMisses
+	}
+	return 0
+}
+
+type SetLogLevelRequest struct {
+	// Level is the name of the new log level, such as
+	// "debug" or "error". The accepted levels are up to
+	// the server.
+	Level string `protobuf:"bytes,1,opt,name=level" json:"level,omitempty"`
+}
+
+func (m *SetLogLevelRequest) Reset()                    { *m = SetLogLevelRequest{} }
+func (m *SetLogLevelRequest) String() string            { return proto1.CompactTextString(m) }
+func (*SetLogLevelRequest) ProtoMessage()               {}
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *SetLogLevelRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type SetLogLevelResponse struct {
+}
+
+func (m *SetLogLevelResponse) Reset()                    { *m = SetLogLevelResponse{} }
+func (m *SetLogLevelResponse) String() string            { return proto1.CompactTextString(m) }
+func (*SetLogLevelResponse) ProtoMessage()               {}
+func (*SetLogLevelResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
 func init() {
 	proto1.RegisterType((*LookupRequest)(nil), "pwned.LookupRequest")
 	proto1.RegisterType((*LookupResponse)(nil), "pwned.LookupResponse")
 	proto1.RegisterType((*RangeRequest)(nil), "pwned.RangeRequest")
 	proto1.RegisterType((*RangeResponse)(nil), "pwned.RangeResponse")
+	proto1.RegisterType((*ListDatasetsRequest)(nil), "pwned.ListDatasetsRequest")
+	proto1.RegisterType((*ListDatasetsResponse)(nil), "pwned.ListDatasetsResponse")
+	proto1.RegisterType((*Dataset)(nil), "pwned.Dataset")
+	proto1.RegisterType((*InfoRequest)(nil), "pwned.InfoRequest")
+	proto1.RegisterType((*InfoResponse)(nil), "pwned.InfoResponse")
+	proto1.RegisterType((*DatasetInfo)(nil), "pwned.DatasetInfo")
+	proto1.RegisterType((*ReloadDatasetRequest)(nil), "pwned.ReloadDatasetRequest")
+	proto1.RegisterType((*ReloadDatasetResponse)(nil), "pwned.ReloadDatasetResponse")
+	proto1.RegisterType((*PurgeCacheRequest)(nil), "pwned.PurgeCacheRequest")
+	proto1.RegisterType((*PurgeCacheResponse)(nil), "pwned.PurgeCacheResponse")
+	proto1.RegisterType((*GetStatsRequest)(nil), "pwned.GetStatsRequest")
+	proto1.RegisterType((*GetStatsResponse)(nil), "pwned.GetStatsResponse")
+	proto1.RegisterType((*MethodStats)(nil), "pwned.MethodStats")
+	proto1.RegisterType((*DatasetStats)(nil), "pwned.DatasetStats")
+	proto1.RegisterType((*SetLogLevelRequest)(nil), "pwned.SetLogLevelRequest")
+	proto1.RegisterType((*SetLogLevelResponse)(nil), "pwned.SetLogLevelResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -128,6 +583,8 @@ const _ = grpc.SupportPackageIsVersion4
 type SearcherClient interface {
 	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
 	Range(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error)
+	ListDatasets(ctx context.Context, in *ListDatasetsRequest, opts ...grpc.CallOption) (*ListDatasetsResponse, error)
+	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
 }
 
 type searcherClient struct {
@@ -156,11 +613,31 @@ func (c *searcherClient) Range(ctx context.Context, in *RangeRequest, opts ...gr
 	return out, nil
 }
 
+func (c *searcherClient) ListDatasets(ctx context.Context, in *ListDatasetsRequest, opts ...grpc.CallOption) (*ListDatasetsResponse, error) {
+	out := new(ListDatasetsResponse)
+	err := grpc.Invoke(ctx, "/pwned.Searcher/ListDatasets", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searcherClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	out := new(InfoResponse)
+	err := grpc.Invoke(ctx, "/pwned.Searcher/Info", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Searcher service
 
 type SearcherServer interface {
 	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
 	Range(context.Context, *RangeRequest) (*RangeResponse, error)
+	ListDatasets(context.Context, *ListDatasetsRequest) (*ListDatasetsResponse, error)
+	Info(context.Context, *InfoRequest) (*InfoResponse, error)
 }
 
 func RegisterSearcherServer(s *grpc.Server, srv SearcherServer) {
@@ -203,6 +680,42 @@ func _Searcher_Range_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Searcher_ListDatasets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDatasetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearcherServer).ListDatasets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pwned.Searcher/ListDatasets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearcherServer).ListDatasets(ctx, req.(*ListDatasetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Searcher_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearcherServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pwned.Searcher/Info",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearcherServer).Info(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Searcher_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "pwned.Searcher",
 	HandlerType: (*SearcherServer)(nil),
@@ -215,6 +728,177 @@ var _Searcher_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Range",
 			Handler:    _Searcher_Range_Handler,
 		},
+		{
+			MethodName: "ListDatasets",
+			Handler:    _Searcher_ListDatasets_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _Searcher_Info_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "pwned.proto",
+}
+
+// Client API for Admin service
+
+type AdminClient interface {
+	ReloadDataset(ctx context.Context, in *ReloadDatasetRequest, opts ...grpc.CallOption) (*ReloadDatasetResponse, error)
+	PurgeCache(ctx context.Context, in *PurgeCacheRequest, opts ...grpc.CallOption) (*PurgeCacheResponse, error)
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error)
+}
+
+type adminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminClient(cc *grpc.ClientConn) AdminClient {
+	return &adminClient{cc}
+}
+
+func (c *adminClient) ReloadDataset(ctx context.Context, in *ReloadDatasetRequest, opts ...grpc.CallOption) (*ReloadDatasetResponse, error) {
+	out := new(ReloadDatasetResponse)
+	err := grpc.Invoke(ctx, "/pwned.Admin/ReloadDataset", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) PurgeCache(ctx context.Context, in *PurgeCacheRequest, opts ...grpc.CallOption) (*PurgeCacheResponse, error) {
+	out := new(PurgeCacheResponse)
+	err := grpc.Invoke(ctx, "/pwned.Admin/PurgeCache", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	out := new(GetStatsResponse)
+	err := grpc.Invoke(ctx, "/pwned.Admin/GetStats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error) {
+	out := new(SetLogLevelResponse)
+	err := grpc.Invoke(ctx, "/pwned.Admin/SetLogLevel", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Admin service
+
+type AdminServer interface {
+	ReloadDataset(context.Context, *ReloadDatasetRequest) (*ReloadDatasetResponse, error)
+	PurgeCache(context.Context, *PurgeCacheRequest) (*PurgeCacheResponse, error)
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error)
+}
+
+func RegisterAdminServer(s *grpc.Server, srv AdminServer) {
+	s.RegisterService(&_Admin_serviceDesc, srv)
+}
+
+func _Admin_ReloadDataset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadDatasetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).ReloadDataset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pwned.Admin/ReloadDataset",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).ReloadDataset(ctx, req.(*ReloadDatasetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_PurgeCache_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeCacheRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).PurgeCache(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pwned.Admin/PurgeCache",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).PurgeCache(ctx, req.(*PurgeCacheRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pwned.Admin/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Admin_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pwned.Admin/SetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Admin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pwned.Admin",
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReloadDataset",
+			Handler:    _Admin_ReloadDataset_Handler,
+		},
+		{
+			MethodName: "PurgeCache",
+			Handler:    _Admin_PurgeCache_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Admin_GetStats_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _Admin_SetLogLevel_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pwned.proto",
@@ -223,19 +907,56 @@ var _Searcher_serviceDesc = grpc.ServiceDesc{
 func init() { proto1.RegisterFile("pwned.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 213 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0x28, 0xcf, 0x4b,
-	0x4d, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x05, 0x73, 0x94, 0xd4, 0xb9, 0x78, 0x7d,
-	0xf2, 0xf3, 0xb3, 0x4b, 0x0b, 0x82, 0x52, 0x0b, 0x4b, 0x53, 0x8b, 0x4b, 0x84, 0xc4, 0xb8, 0xd8,
-	0x52, 0x32, 0xd3, 0x53, 0x8b, 0x4b, 0x24, 0x18, 0x15, 0x18, 0x35, 0x78, 0x82, 0xa0, 0x3c, 0x25,
-	0x35, 0x2e, 0x3e, 0x98, 0xc2, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0x21, 0x11, 0x2e, 0xd6, 0xe4,
-	0xfc, 0xd2, 0x3c, 0x88, 0x42, 0xde, 0x20, 0x08, 0x47, 0x49, 0x8d, 0x8b, 0x27, 0x28, 0x31, 0x2f,
-	0x3d, 0x15, 0xc9, 0xbc, 0x82, 0xa2, 0xd4, 0xb4, 0xcc, 0x0a, 0xb0, 0x32, 0xce, 0x20, 0x28, 0x4f,
-	0x49, 0x93, 0x8b, 0x17, 0xaa, 0x0e, 0x6a, 0x9c, 0x04, 0x17, 0x7b, 0x51, 0x6a, 0x71, 0x69, 0x4e,
-	0x49, 0x31, 0xd4, 0x66, 0x18, 0xd7, 0xa8, 0x92, 0x8b, 0x23, 0x38, 0x35, 0xb1, 0x28, 0x39, 0x23,
-	0xb5, 0x48, 0xc8, 0x9c, 0x8b, 0x0d, 0xe2, 0x0c, 0x21, 0x11, 0x3d, 0x88, 0x77, 0x50, 0x9c, 0x2f,
-	0x25, 0x8a, 0x26, 0x0a, 0x31, 0x5c, 0x89, 0x41, 0xc8, 0x84, 0x8b, 0x15, 0x6c, 0x9f, 0x90, 0x30,
-	0x54, 0x05, 0xb2, 0x2b, 0xa5, 0x44, 0x50, 0x05, 0x61, 0xba, 0x9c, 0x38, 0x3d, 0x18, 0xa3, 0x58,
-	0xc1, 0x01, 0x96, 0xc4, 0x06, 0xa6, 0x8c, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0xd8, 0x6c, 0x27,
-	0x45, 0x46, 0x01, 0x00, 0x00,
+	// 809 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0xcb, 0x4e, 0xdb, 0x4a,
+	0x18, 0x3e, 0x06, 0xe7, 0xf6, 0x3b, 0x09, 0x30, 0x04, 0xf0, 0xf1, 0xe1, 0x48, 0x39, 0x96, 0x0e,
+	0xa4, 0x94, 0xd2, 0x36, 0xad, 0xda, 0x55, 0xa5, 0x02, 0xbd, 0x80, 0x14, 0x24, 0x34, 0xac, 0xda,
+	0x4d, 0x64, 0xe2, 0x21, 0xb1, 0xea, 0xd8, 0xc1, 0x33, 0xa6, 0xed, 0xbe, 0x2f, 0xd2, 0x07, 0xe8,
+	0xf3, 0x75, 0xd1, 0x4d, 0x35, 0x37, 0xc7, 0x4e, 0xac, 0xb2, 0x8a, 0xbf, 0xef, 0xbf, 0xce, 0x7f,
+	0x0b, 0x58, 0xb3, 0xcf, 0x11, 0xf1, 0x8f, 0x66, 0x49, 0xcc, 0x62, 0x54, 0x11, 0xc0, 0x3d, 0x86,
+	0xd6, 0x20, 0x8e, 0x3f, 0xa5, 0x33, 0x4c, 0x6e, 0x53, 0x42, 0x19, 0xda, 0x86, 0xaa, 0x1f, 0x8c,
+	0x09, 0x65, 0xb6, 0xd1, 0x35, 0x7a, 0x4d, 0xac, 0x10, 0xb2, 0xa1, 0xe6, 0x7b, 0xcc, 0xa3, 0x84,
+	0xd9, 0x2b, 0x5d, 0xa3, 0xd7, 0xc0, 0x1a, 0xba, 0x7b, 0xd0, 0xd6, 0x2e, 0xe8, 0x2c, 0x8e, 0x28,
+	0x41, 0x1d, 0xa8, 0x8c, 0xe2, 0x34, 0x92, 0x2e, 0x5a, 0x58, 0x02, 0xf7, 0x35, 0x34, 0xb1, 0x17,
+	0x8d, 0x49, 0x2e, 0xd2, 0x2c, 0x21, 0x37, 0xc1, 0x17, 0xa1, 0xd6, 0xc0, 0x0a, 0xfd, 0x21, 0xd2,
+	0x25, 0xb4, 0x94, 0x07, 0x15, 0xc8, 0x86, 0x5a, 0x42, 0x68, 0x1a, 0x32, 0xaa, 0xb2, 0xd5, 0x10,
+	0xfd, 0x0f, 0x6d, 0x96, 0xa4, 0xd1, 0xc8, 0x63, 0xc4, 0x1f, 0x5e, 0x07, 0x8c, 0x0a, 0x5f, 0x2d,
+	0xdc, 0xca, 0xd8, 0x93, 0x80, 0x51, 0x77, 0x0b, 0x36, 0x07, 0x01, 0x65, 0x6f, 0x64, 0x00, 0xaa,
+	0x52, 0x73, 0x4f, 0xa0, 0x53, 0xa4, 0x55, 0xbc, 0x03, 0xa8, 0xab, 0x5c, 0x78, 0xc0, 0xd5, 0x9e,
+	0xd5, 0x6f, 0x1f, 0xc9, 0xa2, 0x2a, 0x55, 0x9c, 0xc9, 0xdd, 0x6f, 0x06, 0xd4, 0x14, 0x8b, 0x10,
+	0x98, 0x91, 0x37, 0x25, 0xea, 0xa1, 0xe2, 0x9b, 0x3f, 0x3f, 0x14, 0x65, 0x13, 0x99, 0xd5, 0xb1,
+	0x42, 0x25, 0x99, 0xaf, 0x96, 0x64, 0x8e, 0xf6, 0xc0, 0x0c, 0xa2, 0x9b, 0xd8, 0x36, 0xbb, 0x46,
+	0xcf, 0xea, 0xa3, 0x62, 0x1a, 0xe7, 0xd1, 0x4d, 0x8c, 0x85, 0xdc, 0xdd, 0x07, 0x4b, 0x20, 0x55,
+	0xf4, 0x5c, 0x71, 0x8d, 0x62, 0x71, 0x5f, 0x40, 0x53, 0x2a, 0xaa, 0xb7, 0xea, 0x00, 0xc6, 0x3d,
+	0x01, 0x7e, 0x18, 0x60, 0xe5, 0x58, 0x1e, 0xe1, 0x8e, 0x24, 0x34, 0x88, 0x23, 0x1d, 0x41, 0x41,
+	0x2e, 0x21, 0x11, 0x4b, 0x02, 0x22, 0x9b, 0x61, 0x62, 0x0d, 0x91, 0x03, 0x75, 0xd9, 0x7c, 0x22,
+	0x5f, 0x6b, 0xe2, 0x0c, 0xa3, 0x5d, 0x68, 0x78, 0xe1, 0x38, 0x4e, 0x02, 0x36, 0x99, 0x8a, 0xd7,
+	0x36, 0xf0, 0x9c, 0xe0, 0x96, 0x24, 0x1a, 0xc5, 0x7e, 0x10, 0x8d, 0xed, 0x8a, 0x10, 0x66, 0x98,
+	0x8f, 0xe1, 0x75, 0x1a, 0x84, 0xcc, 0xae, 0x76, 0x8d, 0xde, 0x2a, 0x96, 0xc0, 0x7d, 0x02, 0x1d,
+	0x4c, 0xc2, 0xd8, 0xf3, 0x75, 0xcb, 0xee, 0xad, 0xcc, 0x0e, 0x6c, 0x2d, 0x58, 0xc8, 0x12, 0xb9,
+	0x8f, 0x60, 0xe3, 0x32, 0x4d, 0xc6, 0xe4, 0xd4, 0x1b, 0x4d, 0xc8, 0xfd, 0x7e, 0x0e, 0x01, 0xe5,
+	0xd5, 0x55, 0x9d, 0xf9, 0x1a, 0x70, 0xd6, 0x17, 0xea, 0x26, 0x56, 0xc8, 0xdd, 0x80, 0xb5, 0xf7,
+	0x84, 0x5d, 0x31, 0x6f, 0x3e, 0x96, 0xb7, 0xb0, 0x3e, 0xa7, 0x94, 0xf9, 0x21, 0xd4, 0xa6, 0x84,
+	0x4d, 0x62, 0x5f, 0x4f, 0xa4, 0xee, 0xd4, 0x85, 0x60, 0xa5, 0xb2, 0x56, 0x41, 0x8f, 0x73, 0x03,
+	0xbc, 0x22, 0xd4, 0x37, 0x8b, 0x8d, 0x95, 0xfa, 0xf3, 0x29, 0xfe, 0x00, 0x56, 0xce, 0x11, 0x4f,
+	0x56, 0xba, 0xd2, 0x3b, 0x2b, 0x11, 0x6f, 0x43, 0x22, 0x93, 0xd4, 0xbd, 0xcd, 0x30, 0xb7, 0x21,
+	0x49, 0x12, 0x27, 0xba, 0xb5, 0x0a, 0xb9, 0xbf, 0x0c, 0x68, 0xe6, 0xa3, 0x96, 0x6e, 0xc9, 0x43,
+	0xd8, 0x48, 0x67, 0x94, 0x25, 0xc4, 0x9b, 0x0e, 0x17, 0x22, 0xac, 0x6b, 0x01, 0xd6, 0x91, 0xf6,
+	0x61, 0x2d, 0x53, 0x2e, 0x84, 0x6c, 0x6b, 0xfa, 0xad, 0x60, 0xd1, 0x03, 0xc8, 0x8c, 0x87, 0xa1,
+	0xc7, 0x48, 0x34, 0xfa, 0x2a, 0x46, 0xcb, 0xc4, 0x99, 0x83, 0x81, 0xa4, 0xc5, 0x2d, 0xe3, 0xfd,
+	0x12, 0xd3, 0x55, 0xc7, 0x12, 0xa0, 0x7f, 0x01, 0xc4, 0xc7, 0x70, 0xc2, 0x17, 0xb4, 0x2a, 0x4c,
+	0x1b, 0x82, 0x39, 0xe3, 0xcb, 0xf9, 0x1f, 0x34, 0xa5, 0x78, 0x1a, 0x50, 0x4a, 0xa8, 0x5d, 0x13,
+	0x0a, 0x96, 0xe0, 0x2e, 0x04, 0xe5, 0x1e, 0x00, 0xba, 0x22, 0x6c, 0x10, 0x8f, 0x07, 0xe4, 0x8e,
+	0x84, 0x7a, 0x78, 0x3a, 0x50, 0x09, 0x39, 0x56, 0x35, 0x90, 0x80, 0x5f, 0xa9, 0x82, 0xae, 0x6c,
+	0x7d, 0xff, 0xa7, 0x01, 0xf5, 0x2b, 0xe2, 0x25, 0xa3, 0x09, 0x49, 0xd0, 0x4b, 0xa8, 0xca, 0x2b,
+	0x8c, 0x3a, 0xaa, 0xa3, 0x85, 0xbb, 0xee, 0x6c, 0x2d, 0xb0, 0x6a, 0x84, 0xff, 0x42, 0xcf, 0xa1,
+	0x22, 0x8e, 0x2a, 0xd2, 0x93, 0x90, 0x3f, 0xd2, 0x4e, 0xa7, 0x48, 0x66, 0x56, 0xe7, 0xd0, 0xcc,
+	0x5f, 0x48, 0xe4, 0x68, 0xf7, 0xcb, 0xd7, 0xd4, 0xf9, 0xa7, 0x54, 0x96, 0xb9, 0x7a, 0x0a, 0xa6,
+	0x38, 0x1c, 0x7a, 0x70, 0x73, 0xe7, 0xca, 0xd9, 0x2c, 0x70, 0xda, 0xa4, 0xff, 0x7d, 0x05, 0x2a,
+	0xc7, 0xfe, 0x34, 0x88, 0xd0, 0x00, 0x5a, 0x85, 0xdd, 0x44, 0x3a, 0x58, 0xd9, 0x8e, 0x3b, 0xbb,
+	0xe5, 0xc2, 0x2c, 0x95, 0x53, 0x80, 0xf9, 0x86, 0x22, 0x5b, 0x69, 0x2f, 0xed, 0xb8, 0xf3, 0x77,
+	0x89, 0x24, 0x73, 0xf2, 0x0a, 0xea, 0x7a, 0x4b, 0xd1, 0xb6, 0x52, 0x5c, 0xd8, 0x64, 0x67, 0x67,
+	0x89, 0xcf, 0xcc, 0xdf, 0x81, 0x95, 0x6b, 0x36, 0xd2, 0xa1, 0x96, 0x87, 0xc5, 0x71, 0xca, 0x44,
+	0xda, 0xcf, 0x49, 0xe3, 0xcc, 0xf8, 0x58, 0x11, 0xff, 0xf5, 0xd7, 0x55, 0xf1, 0xf3, 0xec, 0xf7,
+	0x00, 0x7d, 0x02, 0xb2, 0xdc, 0x01, 0x08, 0x00, 0x00,
 }
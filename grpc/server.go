@@ -3,11 +3,16 @@ package pwnedgrpc
 import (
 	"context"
 	"crypto/sha1"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.tmthrgd.dev/pwned"
 	pb "go.tmthrgd.dev/pwned/grpc/internal/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/status"
 )
 
@@ -21,21 +26,158 @@ type Lookup interface {
 	Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error)
 }
 
+// Truncated contains an optional method that Ranger's may
+// implement to indicate that the results returned by Range
+// contain truncated hashes, as produced by
+// pwned.AppendTruncatedResult.
+//
+// TruncatedBits returns the number of bits each hash has
+// been truncated to. It must be a multiple of eight
+// between 32 and 160, or zero if the hashes have not been
+// truncated, and must not change.
+type Truncated interface {
+	pwned.Ranger
+	TruncatedBits() int
+}
+
 // Server represents a pwned.Searcher service.
 type Server struct {
+	datasets map[string]*dataset
+	methods  map[string]*counters
+}
+
+// searcherMethods are the RPCs of the pwned.Searcher
+// service that are counted.
+var searcherMethods = []string{"Lookup", "Range", "ListDatasets", "Info"}
+
+// counters records the number of calls made, the number
+// that failed and the total time taken. It must only be
+// accessed atomically.
+type counters struct {
+	requests, errors, nanos uint64
+}
+
+func (c *counters) record(start time.Time, err error) {
+	atomic.AddUint64(&c.requests, 1)
+	atomic.AddUint64(&c.nanos, uint64(time.Since(start)))
+
+	if err != nil {
+		atomic.AddUint64(&c.errors, 1)
+	}
+}
+
+// dataset is a single Ranger served by a Server.
+type dataset struct {
 	ranger pwned.Ranger
 	lookup Lookup
+
+	truncatedBits int
+
+	upstream *counters
 }
 
-// NewServer creates a Server with the given Ranger.
-func NewServer(ranger pwned.Ranger) *Server {
+func newDataset(ranger pwned.Ranger) *dataset {
 	lookup, _ := ranger.(Lookup)
-	return &Server{
+
+	var truncatedBits int
+	if t, ok := ranger.(Truncated); ok {
+		if truncatedBits = t.TruncatedBits(); truncatedBits != 0 {
+			pwned.TruncatedSuffixSize(truncatedBits) // validate
+		}
+	}
+
+	return &dataset{
 		ranger,
 		lookup,
+
+		truncatedBits,
+
+		new(counters),
+	}
+}
+
+// NewServer creates a Server with the given Ranger as
+// the default dataset. ranger may be nil if the Server
+// only serves named datasets.
+func NewServer(ranger pwned.Ranger, opts ...ServerOption) *Server {
+	s := &Server{
+		datasets: make(map[string]*dataset),
+		methods:  make(map[string]*counters, len(searcherMethods)),
 	}
+
+	for _, method := range searcherMethods {
+		s.methods[method] = new(counters)
+	}
+
+	if ranger != nil {
+		s.datasets[""] = newDataset(ranger)
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ServerOption allows the behaviour of the Server to be
+// configured.
+type ServerOption func(*Server)
+
+// WithDataset adds a named dataset to the Server. Clients
+// select it with the UseDataset call option.
+//
+// If name is used more than once, the last dataset given
+// replaces the earlier ones. WithDataset panics if name is
+// empty.
+func WithDataset(name string, ranger pwned.Ranger) ServerOption {
+	if name == "" {
+		panic("pwned: dataset name must not be empty")
+	}
+
+	return func(s *Server) {
+		s.datasets[name] = newDataset(ranger)
+	}
+}
+
+func (s *Server) dataset(name string) (*dataset, error) {
+	ds, ok := s.datasets[name]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown dataset")
+	}
+
+	return ds, nil
+}
+
+// suffixSize returns the size of the suffixes returned by
+// the Ranger.
+func (ds *dataset) suffixSize() int {
+	if ds.truncatedBits != 0 {
+		return pwned.TruncatedSuffixSize(ds.truncatedBits)
+	}
+
+	return pwned.SuffixSize
+}
+
+// track records a call to method that started at start and
+// returned *err.
+func (s *Server) track(method string, start time.Time, err *error) {
+	s.methods[method].record(start, *err)
+}
+
+// setPool holds *[]byte buffers used to search range
+// results in Lookup. Buffers larger than maxPooledSet are
+// not returned to the pool.
+var setPool = sync.Pool{
+	New: func() interface{} {
+		const average = 478
+		buf := make([]byte, 0, pwned.Size(average))
+		return &buf
+	},
 }
 
+const maxPooledSet = 64 << 10
+
 type pbServer struct{ *Server }
 
 // Attach registers the pwned.Searcher service to the
@@ -44,27 +186,50 @@ func (s *Server) Attach(srv *grpc.Server) {
 	pb.RegisterSearcherServer(srv, pbServer{s})
 }
 
-func (s pbServer) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.LookupResponse, error) {
+func (s pbServer) Lookup(ctx context.Context, req *pb.LookupRequest) (resp *pb.LookupResponse, err error) {
+	defer s.track("Lookup", time.Now(), &err)
+
 	if len(req.Digest) != sha1.Size {
 		return nil, status.Error(codes.InvalidArgument, "digest is not SHA1")
 	}
 
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
 	var digest [sha1.Size]byte
 	copy(digest[:], req.Digest)
 
-	var (
-		count int
-		err   error
-	)
-	if s.lookup != nil {
-		count, err = s.lookup.Lookup(ctx, digest)
+	start := time.Now()
+
+	var count int
+	if ds.lookup != nil {
+		count, err = ds.lookup.Lookup(ctx, digest)
+		ds.upstream.record(start, err)
 	} else {
 		prefix, suffix := pwned.SplitDigest(digest)
 
+		buf := setPool.Get().(*[]byte)
+		defer setPool.Put(buf)
+
 		var res []byte
-		res, err = s.ranger.Range(ctx, prefix)
+		res, err = pwned.RangeAppend(ctx, ds.ranger, (*buf)[:0], prefix)
+		ds.upstream.record(start, err)
+
+		if err == nil && len(res)%(ds.suffixSize()+1) != 0 {
+			return nil, status.Error(codes.Internal, "invalid result set returned")
+		}
+
+		if ds.truncatedBits != 0 {
+			count = pwned.SearchTruncatedSet(res, suffix, ds.truncatedBits)
+		} else {
+			count = pwned.SearchSet(res, suffix)
+		}
 
-		count = pwned.SearchSet(res, suffix)
+		if cap(res) > cap(*buf) && cap(res) <= maxPooledSet {
+			*buf = res[:0]
+		}
 	}
 
 	if err != nil {
@@ -76,21 +241,119 @@ func (s pbServer) Lookup(ctx context.Context, req *pb.LookupRequest) (*pb.Lookup
 	}, nil
 }
 
-func (s pbServer) Range(ctx context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+func (s pbServer) Range(ctx context.Context, req *pb.RangeRequest) (resp *pb.RangeResponse, err error) {
+	defer s.track("Range", time.Now(), &err)
+
 	if len(req.Prefix) != pwned.PrefixSize {
 		return nil, status.Error(codes.InvalidArgument, "prefix is wrong size")
 	}
 
-	res, err := s.ranger.Range(ctx, req.Prefix)
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := ds.ranger.Range(ctx, req.Prefix)
+	ds.upstream.record(start, err)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	if len(res)%(pwned.SuffixSize+1) != 0 {
+	if len(res)%(ds.suffixSize()+1) != 0 {
 		return nil, status.Error(codes.Internal, "invalid result set returned")
 	}
 
 	return &pb.RangeResponse{
-		Results: res,
+		Results:       res,
+		TruncatedBits: uint32(ds.truncatedBits),
+	}, nil
+}
+
+func (s pbServer) ListDatasets(ctx context.Context, req *pb.ListDatasetsRequest) (resp *pb.ListDatasetsResponse, err error) {
+	defer s.track("ListDatasets", time.Now(), &err)
+
+	names := make([]string, 0, len(s.datasets))
+	for name := range s.datasets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	resp = &pb.ListDatasetsResponse{
+		Datasets: make([]*pb.Dataset, 0, len(names)),
+	}
+	for _, name := range names {
+		ds := s.datasets[name]
+
+		// A dataset that cannot describe itself is still
+		// listed, so that the others remain visible.
+		info, err := ds.info(ctx)
+		if err != nil {
+			grpclog.Warningf("pwned: failed to get info for dataset %q: %v", name, err)
+		}
+
+		resp.Datasets = append(resp.Datasets, &pb.Dataset{
+			Name:          name,
+			Lookup:        ds.lookup != nil,
+			TruncatedBits: uint32(ds.truncatedBits),
+			Info:          info,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s pbServer) Info(ctx context.Context, req *pb.InfoRequest) (resp *pb.InfoResponse, err error) {
+	defer s.track("Info", time.Now(), &err)
+
+	ds, err := s.dataset(req.Dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := ds.info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if info == nil {
+		return nil, status.Error(codes.Unimplemented, "dataset does not provide info")
+	}
+
+	return &pb.InfoResponse{
+		Info: info,
+	}, nil
+}
+
+// info returns the dataset's pwned.Info, or nil if the
+// Ranger does not implement pwned.InfoRanger or returns
+// pwned.ErrNoInfo.
+func (ds *dataset) info(ctx context.Context) (*pb.DatasetInfo, error) {
+	ir, ok := ds.ranger.(pwned.InfoRanger)
+	if !ok {
+		return nil, nil
+	}
+
+	info, err := ir.Info(ctx)
+	if err == pwned.ErrNoInfo {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	var built int64
+	if !info.Built.IsZero() {
+		built = info.Built.Unix()
+	}
+
+	return &pb.DatasetInfo{
+		Version:   info.Version,
+		Entries:   info.Entries,
+		Prefixes:  info.Prefixes,
+		Algorithm: info.Algorithm,
+		Encoding:  info.Encoding,
+		Built:     built,
 	}, nil
 }
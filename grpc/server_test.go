@@ -0,0 +1,156 @@
+package pwnedgrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/internal/test"
+	"go.tmthrgd.dev/pwned/wordlist"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDatasets(t *testing.T) {
+	t.Parallel()
+
+	c, stop := test.TestingClient(NewServer(wordlist.New("password", "password"),
+		WithDataset("banned-words", wordlist.New("Acme2019!")),
+		WithDataset("hibp", gateway{}),
+	).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	count, err := cc.Search(context.Background(), "password")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = cc.Search(context.Background(), "Acme2019!")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = cc.Search(context.Background(), "Acme2019!", UseDataset("banned-words"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = cc.Lookup(context.Background(), "Acme2019!", UseDataset("banned-words"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	_, err = cc.Search(context.Background(), "password", UseDataset("missing"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	datasets, err := cc.ListDatasets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, datasets, 3)
+
+	for i, want := range []Dataset{
+		{Name: "", Lookup: true},
+		{Name: "banned-words", Lookup: true},
+		{Name: "hibp", Lookup: false},
+	} {
+		assert.Equal(t, want.Name, datasets[i].Name)
+		assert.Equal(t, want.Lookup, datasets[i].Lookup)
+		assert.Equal(t, want.TruncatedBits, datasets[i].TruncatedBits)
+	}
+
+	require.NotNil(t, datasets[0].Info)
+	assert.Equal(t, uint64(1), datasets[0].Info.Entries)
+	assert.Nil(t, datasets[2].Info)
+}
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	c, stop := test.TestingClient(NewServer(wordlist.New("password", "P@ssw0rd", "password"),
+		WithDataset("hibp", gateway{}),
+		WithDataset("wrapped", wrapper{gateway{}}),
+	).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	info, err := cc.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.Entries)
+	assert.Equal(t, "SHA1", info.Algorithm)
+	assert.Equal(t, "wordlist", info.Encoding)
+	assert.WithinDuration(t, time.Now(), info.Built, time.Minute)
+
+	_, err = cc.Info(context.Background(), UseDataset("hibp"))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	_, err = cc.Info(context.Background(), UseDataset("wrapped"))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	datasets, err := cc.ListDatasets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, datasets, 3)
+	assert.Nil(t, datasets[2].Info)
+}
+
+func TestDatasetReplaced(t *testing.T) {
+	t.Parallel()
+
+	c, stop := test.TestingClient(NewServer(nil,
+		WithDataset("words", wordlist.New("password")),
+		WithDataset("words", wordlist.New("P@ssw0rd")),
+	).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	count, err := cc.Search(context.Background(), "P@ssw0rd", UseDataset("words"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = cc.Search(context.Background(), "password", UseDataset("words"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestListDatasetsInfoError(t *testing.T) {
+	t.Parallel()
+
+	c, stop := test.TestingClient(NewServer(wordlist.New("password"),
+		WithDataset("failing", failingInfo{gateway{}}),
+	).Attach)
+	defer stop()
+
+	cc := NewClient(c)
+
+	datasets, err := cc.ListDatasets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, datasets, 2)
+	assert.NotNil(t, datasets[0].Info)
+	assert.Equal(t, "failing", datasets[1].Name)
+	assert.Nil(t, datasets[1].Info)
+
+	_, err = cc.Info(context.Background(), UseDataset("failing"))
+	assert.Error(t, err)
+}
+
+type gateway struct{}
+
+func (gateway) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return nil, nil
+}
+
+// failingInfo is a Ranger whose Info always fails.
+type failingInfo struct{ pwned.Ranger }
+
+func (failingInfo) Info(ctx context.Context) (*pwned.Info, error) {
+	return nil, errors.New("failed")
+}
+
+// wrapper forwards Info to a Ranger that does not have it.
+type wrapper struct{ pwned.Ranger }
+
+func (wrapper) Info(ctx context.Context) (*pwned.Info, error) {
+	return nil, pwned.ErrNoInfo
+}
@@ -0,0 +1,386 @@
+// Package pwnedhttp provides net/http middleware that checks
+// new passwords submitted to a web application against a
+// pwned password list.
+package pwnedhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"google.golang.org/grpc"
+)
+
+// DefaultFields are the field names that are checked by
+// default. They do not include "password", which login
+// forms also use, as a user must be able to sign in with a
+// pwned password in order to change it. Use WithFields and
+// WithRoutes to check a registration form's "password"
+// field.
+var DefaultFields = []string{"new_password", "newPassword"}
+
+// DefaultMaxBodySize is the largest request body that is
+// read by default.
+const DefaultMaxBodySize = 1 << 20
+
+// Result is the outcome of checking a request's password.
+type Result struct {
+	// Field is the name of the field that was checked.
+	Field string
+	// Count is the number of times the password has been
+	// seen. It is zero if it has not been.
+	Count int
+	// Pwned is true if Count met the threshold.
+	Pwned bool
+}
+
+type resultKey struct{}
+
+// ResultFromContext returns the Result of checking the
+// request that ctx belongs to. ok is false if no password
+// was checked, because the route or request did not match
+// or because no configured field was present.
+func ResultFromContext(ctx context.Context) (res Result, ok bool) {
+	res, ok = ctx.Value(resultKey{}).(Result)
+	return res, ok
+}
+
+type checker struct {
+	search func(ctx context.Context, password string) (int, error)
+
+	fields      []string
+	routes      []string
+	threshold   int
+	maxBodySize int64
+
+	passThrough bool
+	reject      http.Handler
+	onError     func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Middleware returns a function that wraps an http.Handler
+// to check the new password submitted to it.
+//
+// The first configured field that is present in the
+// request body is checked. Form, multipart form and JSON
+// bodies are supported, and the body is restored for the
+// wrapped handler. Requests without a body, with any other
+// type of body, or without a matching field, are passed
+// through unchecked and without being read.
+//
+// Bodies larger than the maximum body size are rejected on
+// the routes given to WithRoutes. Without WithRoutes, they
+// are passed through unchecked, so that uploads and other
+// large requests are not affected.
+//
+// Pwned passwords are rejected, unless WithPassThrough is
+// used. The Result is available to the wrapped handler,
+// and to the reject handler, through ResultFromContext.
+//
+// One of WithRanger or WithClient must be given.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	c := &checker{
+		fields:      DefaultFields,
+		threshold:   1,
+		maxBodySize: DefaultMaxBodySize,
+
+		reject:  http.HandlerFunc(defaultReject),
+		onError: defaultError,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.search == nil {
+		panic("pwned/http: WithRanger or WithClient is required")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.serveHTTP(w, r, next)
+		})
+	}
+}
+
+func (c *checker) serveHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if !c.matches(r) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !supported(mediaType) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if r.ContentLength > c.maxBodySize {
+		c.tooLarge(w, r, next)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, c.maxBodySize+1))
+	if err != nil {
+		r.Body.Close()
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(body)) > c.maxBodySize {
+		// Stream the rest of the body after the part that
+		// has already been read.
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		c.tooLarge(w, r, next)
+		return
+	}
+
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	field, password := c.extract(mediaType, params, body)
+	if password == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	count, err := c.search(r.Context(), password)
+	if err != nil {
+		c.onError(w, r, err)
+		return
+	}
+
+	res := Result{
+		Field: field,
+		Count: count,
+		Pwned: count >= c.threshold,
+	}
+	r = r.WithContext(context.WithValue(r.Context(), resultKey{}, res))
+
+	// The wrapped handler may read the body again.
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if res.Pwned && !c.passThrough {
+		c.reject.ServeHTTP(w, r)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+// tooLarge handles a request whose body is larger than the
+// maximum body size.
+func (c *checker) tooLarge(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if c.routes == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+}
+
+func (c *checker) matches(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return false
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+
+	if c.routes == nil {
+		return true
+	}
+
+	for _, route := range c.routes {
+		if r.URL.Path == route ||
+			strings.HasSuffix(route, "/") && strings.HasPrefix(r.URL.Path, route) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// supported reports whether extract can parse bodies of
+// mediaType.
+func supported(mediaType string) bool {
+	switch {
+	case mediaType == "application/x-www-form-urlencoded",
+		mediaType == "multipart/form-data",
+		isJSON(mediaType):
+		return true
+	default:
+		return false
+	}
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// extract returns the first configured field present in
+// body.
+func (c *checker) extract(mediaType string, params map[string]string, body []byte) (field, password string) {
+	var get func(name string) string
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", ""
+		}
+
+		get = values.Get
+	case mediaType == "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(c.maxBodySize)
+		if err != nil {
+			return "", ""
+		}
+		defer form.RemoveAll()
+
+		get = func(name string) string {
+			if v := form.Value[name]; len(v) > 0 {
+				return v[0]
+			}
+
+			return ""
+		}
+	case isJSON(mediaType):
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(body, &values); err != nil {
+			return "", ""
+		}
+
+		get = func(name string) string {
+			var v string
+			json.Unmarshal(values[name], &v)
+			return v
+		}
+	default:
+		return "", ""
+	}
+
+	for _, field := range c.fields {
+		if password := get(field); password != "" {
+			return field, password
+		}
+	}
+
+	return "", ""
+}
+
+func defaultReject(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "This password has appeared in a data breach and cannot be used. Please choose a different password.",
+		http.StatusUnprocessableEntity)
+}
+
+func defaultError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, "The password could not be checked. Please try again later.",
+		http.StatusServiceUnavailable)
+}
+
+// Option allows the behaviour of the middleware to be
+// configured.
+type Option func(*checker)
+
+// WithRanger checks passwords against r with pwned.Search.
+func WithRanger(r pwned.Ranger) Option {
+	return func(c *checker) {
+		c.search = func(ctx context.Context, password string) (int, error) {
+			return pwned.Search(ctx, r, password)
+		}
+	}
+}
+
+// WithClient checks passwords against a pwned server with
+// c.Search. opts are passed to each call.
+func WithClient(client *pwnedgrpc.Client, opts ...grpc.CallOption) Option {
+	return func(c *checker) {
+		c.search = func(ctx context.Context, password string) (int, error) {
+			return client.Search(ctx, password, opts...)
+		}
+	}
+}
+
+// WithFields sets the names of the fields that hold the new
+// password. The first that is present is checked. It
+// defaults to DefaultFields.
+func WithFields(names ...string) Option {
+	return func(c *checker) {
+		c.fields = names
+	}
+}
+
+// WithRoutes limits checking to requests for the given
+// paths. A path ending in '/' matches every path below it.
+// By default, every POST, PUT and PATCH request is checked.
+func WithRoutes(paths ...string) Option {
+	return func(c *checker) {
+		c.routes = append(c.routes, paths...)
+	}
+}
+
+// WithThreshold sets the number of times a password must
+// have been seen to be rejected. It defaults to one.
+func WithThreshold(n int) Option {
+	if n < 1 {
+		panic("pwned/http: threshold must be positive")
+	}
+
+	return func(c *checker) {
+		c.threshold = n
+	}
+}
+
+// WithMaxBodySize sets the largest request body that will
+// be read. Larger requests to the routes given to
+// WithRoutes are rejected with 413 Request Entity Too
+// Large, and other larger requests are passed through
+// unchecked. It defaults to DefaultMaxBodySize.
+func WithMaxBodySize(n int64) Option {
+	return func(c *checker) {
+		c.maxBodySize = n
+	}
+}
+
+// WithRejectHandler sets the handler that responds to
+// requests with a pwned password. By default they are
+// rejected with 422 Unprocessable Entity.
+func WithRejectHandler(h http.Handler) Option {
+	return func(c *checker) {
+		c.reject = h
+	}
+}
+
+// WithErrorHandler sets the function that responds to
+// requests whose password could not be checked. By default
+// they are rejected with 503 Service Unavailable.
+func WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(c *checker) {
+		c.onError = fn
+	}
+}
+
+// WithPassThrough passes requests with a pwned password to
+// the wrapped handler, rather than rejecting them. The
+// handler should use ResultFromContext to act on the
+// result.
+func WithPassThrough() Option {
+	return func(c *checker) {
+		c.passThrough = true
+	}
+}
@@ -0,0 +1,198 @@
+package pwnedhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+// echo responds with the request body and the Result.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	res, ok := ResultFromContext(r.Context())
+	if ok {
+		w.Header().Set("X-Field", res.Field)
+		if res.Pwned {
+			w.Header().Set("X-Pwned", "1")
+		}
+	}
+
+	w.Write(body)
+})
+
+func serve(h http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+const form = "application/x-www-form-urlencoded"
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(WithRanger(wordlist.New("password", "hunter2")),
+		WithFields("new_password", "password"), WithRoutes("/register", "/account/"))(echo)
+
+	body := url.Values{"username": {"alice"}, "password": {"password"}}.Encode()
+	w := serve(h, http.MethodPost, "/register", form, body)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.NotContains(t, w.Body.String(), "password=")
+
+	w = serve(h, http.MethodPost, "/register", form, "username=alice&password=correct+horse+battery+staple")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "password", w.Header().Get("X-Field"))
+	assert.Equal(t, "username=alice&password=correct+horse+battery+staple", w.Body.String())
+
+	w = serve(h, http.MethodPut, "/account/password", "application/json; charset=utf-8",
+		`{"password": "old password", "new_password": "hunter2"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = serve(h, http.MethodPost, "/login", form, body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-Field"))
+
+	w = serve(h, http.MethodGet, "/register?password=password", form, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(h, http.MethodPost, "/register", "text/plain", "password")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "password", w.Body.String())
+}
+
+func TestMiddlewareMultipart(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("username", "alice")
+	mw.WriteField("newPassword", "hunter2")
+	require.NoError(t, mw.Close())
+
+	h := Middleware(WithRanger(wordlist.New("hunter2")))(echo)
+
+	w := serve(h, http.MethodPost, "/", mw.FormDataContentType(), buf.String())
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestMiddlewarePassThrough(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(WithRanger(wordlist.New("hunter2")),
+		WithFields("pw"), WithPassThrough())(echo)
+
+	w := serve(h, http.MethodPost, "/", form, "password=x&pw=hunter2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "pw", w.Header().Get("X-Field"))
+	assert.Equal(t, "1", w.Header().Get("X-Pwned"))
+	assert.Equal(t, "password=x&pw=hunter2", w.Body.String())
+}
+
+func TestMiddlewareThreshold(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(WithRanger(wordlist.New("hunter2", "password", "password")),
+		WithFields("password"), WithThreshold(2), WithRejectHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, _ := ResultFromContext(r.Context())
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte(res.Field))
+		})))(echo)
+
+	w := serve(h, http.MethodPost, "/", form, "password=hunter2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-Pwned"))
+
+	w = serve(h, http.MethodPost, "/", form, "password=password")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "password", w.Body.String())
+}
+
+func TestMiddlewareDefaults(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(WithRanger(wordlist.New("hunter2")), WithMaxBodySize(8))(echo)
+
+	upload := strings.Repeat("x", 64)
+	w := serve(h, http.MethodPost, "/upload", "application/octet-stream", upload)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, upload, w.Body.String())
+
+	w = serve(h, http.MethodPost, "/", form, "new_password=hunter2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "new_password=hunter2", w.Body.String())
+
+	h = Middleware(WithRanger(wordlist.New("hunter2")))(echo)
+
+	w = serve(h, http.MethodPost, "/login", form, "username=alice&password=hunter2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-Field"))
+
+	w = serve(h, http.MethodPost, "/account", form, "password=x&newPassword=hunter2")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestMiddlewareLargeUpload(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("new_password", "hunter2")
+	fw, err := mw.CreateFormFile("upload", "upload.bin")
+	require.NoError(t, err)
+	fw.Write(bytes.Repeat([]byte{0xaa}, 2<<20))
+	require.NoError(t, mw.Close())
+
+	h := Middleware(WithRanger(wordlist.New("hunter2")))(echo)
+
+	w := serve(h, http.MethodPost, "/upload", mw.FormDataContentType(), buf.String())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, buf.Len(), w.Body.Len())
+
+	// Without a Content-Length, the body is streamed on
+	// after the part that was read.
+	req := httptest.NewRequest(http.MethodPost, "/upload", ioutil.NopCloser(bytes.NewReader(buf.Bytes())))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.ContentLength = -1
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, buf.Bytes(), w.Body.Bytes())
+}
+
+type failingRanger struct{}
+
+func (failingRanger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	t.Parallel()
+
+	h := Middleware(WithRanger(failingRanger{}))(echo)
+
+	w := serve(h, http.MethodPost, "/", form, "new_password=hunter2")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	h = Middleware(WithRanger(failingRanger{}), WithMaxBodySize(8), WithRoutes("/"))(echo)
+
+	w = serve(h, http.MethodPost, "/", form, "new_password=hunter2")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	assert.Panics(t, func() { Middleware() })
+}
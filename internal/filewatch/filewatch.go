@@ -0,0 +1,83 @@
+// Package filewatch detects changes to a file by
+// periodically comparing its size and modification time.
+package filewatch
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher watches a single file for changes in the
+// background.
+type Watcher struct {
+	path string
+
+	modTime int64
+	size    int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Watch records the current state of path and then checks
+// it once per interval, calling fn whenever it has changed,
+// until Stop is called. fn is never called concurrently.
+//
+// If the file cannot be stat'd, it is treated as unchanged.
+func Watch(path string, interval time.Duration, fn func()) *Watcher {
+	w := &Watcher{
+		path: path,
+
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	w.changed()
+
+	go w.run(interval, fn)
+	return w
+}
+
+func (w *Watcher) run(interval time.Duration, fn func()) {
+	defer close(w.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			if w.changed() {
+				fn()
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// changed reports whether the file has changed since the
+// last time changed returned true. It must only be called
+// from Watch and run.
+func (w *Watcher) changed() bool {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+
+	modTime, size := fi.ModTime().UnixNano(), fi.Size()
+	if modTime == w.modTime && size == w.size {
+		return false
+	}
+
+	w.modTime, w.size = modTime, size
+	return true
+}
+
+// Stop stops watching the file. It waits for any call to
+// fn to return. It is safe to call Stop more than once.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
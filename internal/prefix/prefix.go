@@ -0,0 +1,37 @@
+// Package prefix converts hexadecimal digest prefixes to
+// and from dense bucket indices.
+package prefix
+
+import "go.tmthrgd.dev/pwned"
+
+// Count is the number of distinct prefixes.
+const Count = 1 << (4 * pwned.PrefixSize)
+
+// Index returns the bucket index of a hexadecimal prefix.
+// Both upper and lower case characters are accepted. It
+// returns false if prefix is not a valid prefix.
+func Index(prefix string) (idx int, ok bool) {
+	if len(prefix) != pwned.PrefixSize {
+		return 0, false
+	}
+
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+
+		var v byte
+		switch {
+		case '0' <= c && c <= '9':
+			v = c - '0'
+		case 'a' <= c && c <= 'f':
+			v = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			return 0, false
+		}
+
+		idx = idx<<4 | int(v)
+	}
+
+	return idx, true
+}
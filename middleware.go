@@ -0,0 +1,83 @@
+package pwned
+
+import (
+	"context"
+	"crypto/sha1"
+)
+
+// Middleware wraps a Ranger to add behaviour, such as
+// caching or metrics, to it.
+//
+// A Middleware may drop the optional methods of the
+// Ranger it wraps, such as the Lookup method of
+// pwnedgrpc.Lookup. Chain restores Lookup when that
+// happens.
+type Middleware func(Ranger) Ranger
+
+// Chain returns a Middleware that applies each of the
+// given middleware in turn. The first middleware is the
+// outermost, so it sees each request first.
+//
+// If a Ranger has a Lookup method, as described by
+// pwnedgrpc.Lookup, but the Ranger returned by a
+// middleware wrapping it does not, Chain adds a Lookup
+// method that forwards to the wrapped Ranger's, so that
+// efficient lookups are not replaced by range queries.
+// Lookups therefore bypass any middleware that drops
+// Lookup. The added method comes with TruncatedBits and
+// Info methods that forward to the middleware's Ranger.
+func Chain(middleware ...Middleware) Middleware {
+	return func(r Ranger) Ranger {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			inner, hasLookup := r.(lookuper)
+
+			r = middleware[i](r)
+
+			if _, ok := r.(lookuper); hasLookup && !ok {
+				r = forwardLookup{r, inner}
+			}
+		}
+
+		return r
+	}
+}
+
+// lookuper matches pwnedgrpc.Lookup which cannot be
+// imported here.
+type lookuper interface {
+	Ranger
+	Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error)
+}
+
+// truncated matches pwnedgrpc.Truncated.
+type truncated interface {
+	Ranger
+	TruncatedBits() int
+}
+
+// forwardLookup adds the Lookup method of inner to a
+// Ranger that wraps inner but dropped it.
+type forwardLookup struct {
+	Ranger
+	inner lookuper
+}
+
+func (s forwardLookup) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	return s.inner.Lookup(ctx, digest)
+}
+
+func (s forwardLookup) TruncatedBits() int {
+	if t, ok := s.Ranger.(truncated); ok {
+		return t.TruncatedBits()
+	}
+
+	return 0
+}
+
+func (s forwardLookup) Info(ctx context.Context) (*Info, error) {
+	if ir, ok := s.Ranger.(InfoRanger); ok {
+		return ir.Info(ctx)
+	}
+
+	return nil, ErrNoInfo
+}
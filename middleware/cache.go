@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+)
+
+// Cache returns a pwned.Middleware that caches up to size
+// range results in memory. Results are evicted after ttl,
+// or only when the cache is full if ttl is zero.
+//
+// The returned Ranger always implements pwnedgrpc.Lookup,
+// by searching the cached range results, and also
+// implements pwnedgrpc.Cache. Each Ranger the middleware
+// wraps has its own cache.
+func Cache(size int, ttl time.Duration) pwned.Middleware {
+	return func(next pwned.Ranger) pwned.Ranger {
+		return &cache{
+			forward: forward{next},
+
+			size: size,
+			ttl:  ttl,
+
+			lru:     list.New(),
+			entries: make(map[string]*list.Element),
+		}
+	}
+}
+
+type cache struct {
+	forward
+
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+
+	hits, misses uint64
+}
+
+type cacheEntry struct {
+	prefix  string
+	set     []byte
+	expires time.Time
+}
+
+func (c *cache) Range(ctx context.Context, prefix string) ([]byte, error) {
+	prefix = strings.ToLower(prefix)
+
+	if set, ok := c.get(prefix); ok {
+		return append([]byte(nil), set...), nil
+	}
+
+	set, err := c.next.Range(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	c.add(prefix, append([]byte(nil), set...))
+	return set, nil
+}
+
+func (c *cache) get(prefix string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[prefix]
+	if ok && c.ttl > 0 && time.Now().After(e.Value.(*cacheEntry).expires) {
+		c.lru.Remove(e)
+		delete(c.entries, prefix)
+		ok = false
+	}
+
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).set, true
+}
+
+func (c *cache) add(prefix string, set []byte) {
+	if c.size <= 0 {
+		return
+	}
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[prefix]; ok {
+		e.Value = &cacheEntry{prefix, set, expires}
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[prefix] = c.lru.PushFront(&cacheEntry{prefix, set, expires})
+
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*cacheEntry).prefix)
+	}
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (c *cache) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	res, err := c.Range(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	if bits := c.TruncatedBits(); bits != 0 {
+		if len(res)%(pwned.TruncatedSuffixSize(bits)+1) != 0 {
+			return 0, errors.New("pwned/middleware: invalid result set returned")
+		}
+
+		return pwned.SearchTruncatedSet(res, suffix, bits), nil
+	}
+
+	if len(res)%(pwned.SuffixSize+1) != 0 {
+		return 0, errors.New("pwned/middleware: invalid result set returned")
+	}
+
+	return pwned.SearchSet(res, suffix), nil
+}
+
+// Purge implements pwnedgrpc.Cache.
+func (c *cache) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.lru.Len()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	return n
+}
+
+// CacheStats implements pwnedgrpc.Cache.
+func (c *cache) CacheStats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+)
+
+// Observer is called after each request made to a Ranger
+// wrapped by Metrics. method is either "Range" or "Lookup"
+// and prefix is the prefix being queried. Lookup digests
+// are never passed to the Observer.
+type Observer func(ctx context.Context, method, prefix string, latency time.Duration, err error)
+
+// Metrics returns a pwned.Middleware that calls obs after
+// each request with the time taken and any error returned.
+func Metrics(obs Observer) pwned.Middleware {
+	return func(next pwned.Ranger) pwned.Ranger {
+		return intercept(next, func(ctx context.Context, method, prefix string, call func(context.Context) error) error {
+			start := time.Now()
+			err := call(ctx)
+			obs(ctx, method, prefix, time.Since(start), err)
+			return err
+		})
+	}
+}
+
+// Logging returns a pwned.Middleware that logs each
+// request with logf, which may be log.Printf. Only the
+// prefix of each request is logged.
+func Logging(logf func(format string, v ...interface{})) pwned.Middleware {
+	return Metrics(func(ctx context.Context, method, prefix string, latency time.Duration, err error) {
+		if err != nil {
+			logf("pwned: %s %s failed after %s: %v", method, prefix, latency, err)
+		} else {
+			logf("pwned: %s %s took %s", method, prefix, latency)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+// Package middleware provides common pwned.Middleware's
+// for caching, metrics, logging, deduplication and rate
+// limiting.
+//
+// Each Ranger returned by a middleware implements
+// pwnedgrpc.Lookup if the Ranger it wraps does. Each also
+// implements pwnedgrpc.Truncated and pwned.InfoRanger,
+// forwarding to the Ranger it wraps when it implements
+// them.
+package middleware
+
+import (
+	"context"
+	"crypto/sha1"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+// forward implements pwnedgrpc.Truncated and
+// pwned.InfoRanger by forwarding to next.
+type forward struct {
+	next pwned.Ranger
+}
+
+// TruncatedBits implements pwnedgrpc.Truncated. It
+// returns zero if the wrapped Ranger is not truncated.
+func (f forward) TruncatedBits() int {
+	if t, ok := f.next.(pwnedgrpc.Truncated); ok {
+		return t.TruncatedBits()
+	}
+
+	return 0
+}
+
+// Info implements pwned.InfoRanger. If the wrapped Ranger
+// does not implement pwned.InfoRanger, pwned.ErrNoInfo is
+// returned.
+func (f forward) Info(ctx context.Context) (*pwned.Info, error) {
+	if ir, ok := f.next.(pwned.InfoRanger); ok {
+		return ir.Info(ctx)
+	}
+
+	return nil, pwned.ErrNoInfo
+}
+
+// interceptFunc is called around each call to the wrapped
+// Ranger. method is either "Range" or "Lookup" and prefix
+// is the prefix being queried. call must be called at most
+// once.
+type interceptFunc func(ctx context.Context, method, prefix string, call func(context.Context) error) error
+
+type interceptor struct {
+	forward
+	fn interceptFunc
+}
+
+type lookupInterceptor struct {
+	*interceptor
+	lookup pwnedgrpc.Lookup
+}
+
+// intercept returns a Ranger that calls fn around each
+// call to next.
+func intercept(next pwned.Ranger, fn interceptFunc) pwned.Ranger {
+	i := &interceptor{forward{next}, fn}
+
+	if lookup, ok := next.(pwnedgrpc.Lookup); ok {
+		return lookupInterceptor{i, lookup}
+	}
+
+	return i
+}
+
+func (i *interceptor) Range(ctx context.Context, prefix string) (res []byte, err error) {
+	err = i.fn(ctx, "Range", prefix, func(ctx context.Context) error {
+		var err error
+		res, err = i.next.Range(ctx, prefix)
+		return err
+	})
+	return res, err
+}
+
+func (i lookupInterceptor) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, _ := pwned.SplitDigest(digest)
+
+	err = i.fn(ctx, "Lookup", prefix, func(ctx context.Context) error {
+		var err error
+		count, err = i.lookup.Lookup(ctx, digest)
+		return err
+	})
+	return count, err
+}
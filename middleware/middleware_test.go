@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+// counter counts the calls made to a Ranger.
+type counter struct {
+	pwned.Ranger
+	calls int32
+	block chan struct{}
+}
+
+func (c *counter) Range(ctx context.Context, prefix string) ([]byte, error) {
+	atomic.AddInt32(&c.calls, 1)
+
+	if c.block != nil {
+		<-c.block
+	}
+
+	return c.Ranger.Range(ctx, prefix)
+}
+
+func TestLookupForwarded(t *testing.T) {
+	t.Parallel()
+
+	r := wordlist.New("password")
+	for _, mw := range []pwned.Middleware{
+		Metrics(func(context.Context, string, string, time.Duration, error) {}),
+		Singleflight(),
+		RateLimit(1000, 10),
+		Cache(10, 0),
+	} {
+		_, ok := mw(r).(pwnedgrpc.Lookup)
+		assert.True(t, ok)
+
+		wrapped := mw(&counter{Ranger: r})
+		_, ok = wrapped.(pwnedgrpc.Truncated)
+		assert.True(t, ok)
+		ir, ok := wrapped.(pwned.InfoRanger)
+		require.True(t, ok)
+		_, err := ir.Info(context.Background())
+		assert.Equal(t, pwned.ErrNoInfo, err)
+
+		info, err := mw(r).(pwned.InfoRanger).Info(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "wordlist", info.Encoding)
+	}
+
+	_, ok := Metrics(nil)(&counter{Ranger: r}).(pwnedgrpc.Lookup)
+	assert.False(t, ok)
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	c := &counter{Ranger: wordlist.New("password")}
+	r := Cache(10, 0)(c)
+
+	digest := sha1.Sum([]byte("password"))
+	prefix, _ := pwned.SplitDigest(digest)
+
+	for i := 0; i < 3; i++ {
+		count, err := r.(pwnedgrpc.Lookup).Lookup(context.Background(), digest)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+
+	set, err := r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Len(t, set, pwned.Size(1))
+	assert.Equal(t, int32(1), c.calls)
+
+	hits, misses := r.(pwnedgrpc.Cache).CacheStats()
+	assert.Equal(t, uint64(3), hits)
+	assert.Equal(t, uint64(1), misses)
+
+	assert.Equal(t, 1, r.(pwnedgrpc.Cache).Purge())
+
+	_, err = r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), c.calls)
+
+	r = Cache(10, time.Nanosecond)(c)
+	for i := 0; i < 2; i++ {
+		_, err = r.Range(context.Background(), prefix)
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, int32(4), c.calls)
+}
+
+func TestSingleflight(t *testing.T) {
+	t.Parallel()
+
+	c := &counter{Ranger: wordlist.New("password"), block: make(chan struct{})}
+	r := Singleflight()(c)
+
+	prefix, _ := pwned.SplitDigest(sha1.Sum([]byte("password")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			set, err := r.Range(context.Background(), prefix)
+			assert.NoError(t, err)
+			assert.Len(t, set, pwned.Size(1))
+		}()
+	}
+
+	for atomic.LoadInt32(&c.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(c.block)
+	wg.Wait()
+
+	assert.True(t, atomic.LoadInt32(&c.calls) < 5)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	var methods, prefixes []string
+	r := Metrics(func(ctx context.Context, method, prefix string, latency time.Duration, err error) {
+		methods = append(methods, method)
+		prefixes = append(prefixes, prefix)
+	})(wordlist.New("password"))
+
+	digest := sha1.Sum([]byte("password"))
+	prefix, _ := pwned.SplitDigest(digest)
+
+	_, err := r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	_, err = r.(pwnedgrpc.Lookup).Lookup(context.Background(), digest)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Range", "Lookup"}, methods)
+	assert.Equal(t, []string{prefix, prefix}, prefixes)
+
+	var logged []string
+	r = Logging(func(format string, v ...interface{}) {
+		logged = append(logged, format)
+	})(failing{})
+
+	_, err = r.Range(context.Background(), prefix)
+	assert.Error(t, err)
+	assert.Len(t, logged, 1)
+}
+
+type failing struct{}
+
+func (failing) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	r := RateLimit(10, 2)(wordlist.New("password"))
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := r.Range(context.Background(), "5baa6")
+		require.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.Range(ctx, "5baa6")
+	assert.Equal(t, context.Canceled, err)
+
+	assert.Panics(t, func() { RateLimit(0, 1) })
+	assert.Panics(t, func() { RateLimit(1, 0) })
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+)
+
+// RateLimit returns a pwned.Middleware that allows at most
+// limit requests per second, with bursts of up to burst
+// requests. Requests over the limit wait until they are
+// allowed or their context is done. The limit is shared
+// by every Ranger the middleware wraps.
+//
+// RateLimit panics if limit is not positive or burst is
+// less than one.
+func RateLimit(limit float64, burst int) pwned.Middleware {
+	if limit <= 0 || math.IsInf(limit, 0) || math.IsNaN(limit) {
+		panic("pwned/middleware: invalid rate limit")
+	}
+	if burst < 1 {
+		panic("pwned/middleware: invalid rate limit burst")
+	}
+
+	l := &limiter{
+		limit:  limit,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+
+	return func(next pwned.Ranger) pwned.Ranger {
+		return intercept(next, func(ctx context.Context, method, prefix string, call func(context.Context) error) error {
+			if err := l.wait(ctx); err != nil {
+				return err
+			}
+
+			return call(ctx)
+		})
+	}
+}
+
+// limiter is a token bucket.
+type limiter struct {
+	limit, burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token from the bucket and returns how
+// long to wait before it is available.
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.limit)
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.limit * float64(time.Second))
+}
+
+// cancel returns a reserved token to the bucket.
+func (l *limiter) cancel() {
+	l.mu.Lock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+	l.mu.Unlock()
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	d := l.reserve()
+	if d == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
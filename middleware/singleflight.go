@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha1"
+	"strings"
+	"sync"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+// Singleflight returns a pwned.Middleware that merges
+// concurrent requests for the same prefix, or for the same
+// digest with Lookup, into a single request to the wrapped
+// Ranger.
+//
+// The merged request uses the context of the first caller,
+// so if it is cancelled every waiting caller receives the
+// error.
+func Singleflight() pwned.Middleware {
+	return func(next pwned.Ranger) pwned.Ranger {
+		s := &singleflight{
+			forward: forward{next},
+			calls:   make(map[string]*flight),
+		}
+
+		if lookup, ok := next.(pwnedgrpc.Lookup); ok {
+			return singleflightLookup{s, lookup}
+		}
+
+		return s
+	}
+}
+
+type singleflight struct {
+	forward
+
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type singleflightLookup struct {
+	*singleflight
+	lookup pwnedgrpc.Lookup
+}
+
+// flight is an in-progress or completed request.
+type flight struct {
+	wg   sync.WaitGroup
+	dups int
+
+	set   []byte
+	count int
+	err   error
+}
+
+// do calls fn once for all concurrent callers with the
+// same key. shared is true if the result of fn was, or may
+// be, returned to more than one caller.
+func (s *singleflight) do(key string, fn func(f *flight)) (f *flight, shared bool) {
+	s.mu.Lock()
+	if f, ok := s.calls[key]; ok {
+		f.dups++
+		s.mu.Unlock()
+		f.wg.Wait()
+		return f, true
+	}
+
+	f = new(flight)
+	f.wg.Add(1)
+	s.calls[key] = f
+	s.mu.Unlock()
+
+	fn(f)
+
+	s.mu.Lock()
+	delete(s.calls, key)
+	shared = f.dups > 0
+	s.mu.Unlock()
+
+	f.wg.Done()
+	return f, shared
+}
+
+func (s *singleflight) Range(ctx context.Context, prefix string) ([]byte, error) {
+	f, shared := s.do("r"+strings.ToLower(prefix), func(f *flight) {
+		f.set, f.err = s.next.Range(ctx, prefix)
+	})
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	if shared {
+		// Callers may modify the returned slice.
+		return append([]byte(nil), f.set...), nil
+	}
+
+	return f.set, nil
+}
+
+func (s singleflightLookup) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	f, _ := s.do("l"+string(digest[:]), func(f *flight) {
+		f.count, f.err = s.lookup.Lookup(ctx, digest)
+	})
+	return f.count, f.err
+}
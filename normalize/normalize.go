@@ -0,0 +1,154 @@
+// Package normalize prepares passwords for hashing.
+//
+// The same visible password can be encoded in several ways,
+// such as with precomposed or combining accents, and each
+// encoding has a different hash. NIST SP 800-63B recommends
+// normalising passwords with NFKC before they are hashed.
+// As the pwned password lists contain passwords as they
+// were typed, both the normalised and original forms are
+// worth checking.
+package normalize
+
+import (
+	"errors"
+	"strconv"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Form is the form of a password that was hashed.
+type Form int
+
+// The forms of a password.
+const (
+	// None indicates that no form of the password matched.
+	None Form = iota
+	// Original is the password as given.
+	Original
+	// NFKC is the password after NFKC normalisation.
+	NFKC
+)
+
+func (f Form) String() string {
+	switch f {
+	case None:
+		return "none"
+	case Original:
+		return "original"
+	case NFKC:
+		return "NFKC"
+	default:
+		return "Form(" + strconv.Itoa(int(f)) + ")"
+	}
+}
+
+// InvalidUTF8 chooses what happens to passwords that are
+// not valid UTF-8.
+type InvalidUTF8 int
+
+// The ways of handling invalid UTF-8.
+const (
+	// Reject returns ErrInvalidUTF8.
+	Reject InvalidUTF8 = iota
+	// Replace replaces each invalid byte with U+FFFD before
+	// normalising.
+	Replace
+	// Raw hashes the password as given, without
+	// normalising it.
+	Raw
+)
+
+// ErrInvalidUTF8 is returned for passwords that are not
+// valid UTF-8 when Reject is used.
+var ErrInvalidUTF8 = errors.New("pwned/normalize: password is not valid UTF-8")
+
+// String returns the NFKC form of password. invalid
+// chooses what happens if password is not valid UTF-8.
+func String(password string, invalid InvalidUTF8) (string, error) {
+	if !utf8.ValidString(password) {
+		switch invalid {
+		case Replace:
+			password = replaceInvalid(password)
+		case Raw:
+			return password, nil
+		default:
+			return "", ErrInvalidUTF8
+		}
+	}
+
+	return norm.NFKC.String(password), nil
+}
+
+// Candidate is a form of a password to be hashed.
+type Candidate struct {
+	Form     Form
+	Password string
+}
+
+// Forms returns the forms of password that should be
+// checked, in order of preference: the NFKC form and then
+// the original if it differs. invalid chooses what happens
+// if password is not valid UTF-8; with Raw, only the
+// original is returned.
+func Forms(password string, invalid InvalidUTF8) ([]Candidate, error) {
+	normalized, err := String(password, invalid)
+	if err != nil {
+		return nil, err
+	}
+
+	if normalized == password {
+		// Normalisation was a no-op, so the password is
+		// already in NFKC form and is reported as such,
+		// unless it is invalid UTF-8 that was left as is.
+		form := NFKC
+		if invalid == Raw && !utf8.ValidString(password) {
+			form = Original
+		}
+
+		return []Candidate{{form, password}}, nil
+	}
+
+	return []Candidate{
+		{NFKC, normalized},
+		{Original, password},
+	}, nil
+}
+
+// Expand returns passwords with the NFKC form of each
+// password added where it differs from the original. It is
+// intended for building stores, such as wordlists, that
+// will be searched with normalised passwords.
+func Expand(passwords []string, invalid InvalidUTF8) ([]string, error) {
+	expanded := make([]string, 0, len(passwords))
+	for _, password := range passwords {
+		expanded = append(expanded, password)
+
+		normalized, err := String(password, invalid)
+		if err != nil {
+			return nil, err
+		}
+
+		if normalized != password {
+			expanded = append(expanded, normalized)
+		}
+	}
+
+	return expanded, nil
+}
+
+func replaceInvalid(s string) string {
+	b := make([]byte, 0, len(s)+utf8.UTFMax)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "\uFFFD"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+
+		i += size
+	}
+
+	return string(b)
+}
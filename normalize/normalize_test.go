@@ -0,0 +1,75 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	t.Parallel()
+
+	for password, want := range map[string]string{
+		"password":             "password",
+		"cafe\u0301":           "caf\u00e9",
+		"caf\u00e9":            "caf\u00e9",
+		"ｐａｓｓｗｏｒｄ":             "password",
+		"\ufb01sh":             "fish",
+		"Ⅻ":                    "XII",
+		"correct horse staple": "correct horse staple",
+	} {
+		got, err := String(password, Reject)
+		require.NoError(t, err, password)
+		assert.Equal(t, want, got, password)
+	}
+}
+
+func TestStringInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := String("pass\xffword", Reject)
+	assert.Equal(t, ErrInvalidUTF8, err)
+
+	got, err := String("cafe\u0301\xff", Replace)
+	require.NoError(t, err)
+	assert.Equal(t, "caf\u00e9\ufffd", got)
+
+	got, err = String("cafe\u0301\xff", Raw)
+	require.NoError(t, err)
+	assert.Equal(t, "cafe\u0301\xff", got)
+}
+
+func TestForms(t *testing.T) {
+	t.Parallel()
+
+	forms, err := Forms("password", Reject)
+	require.NoError(t, err)
+	assert.Equal(t, []Candidate{{NFKC, "password"}}, forms)
+
+	forms, err = Forms("cafe\u0301", Reject)
+	require.NoError(t, err)
+	assert.Equal(t, []Candidate{{NFKC, "caf\u00e9"}, {Original, "cafe\u0301"}}, forms)
+
+	forms, err = Forms("\xff", Raw)
+	require.NoError(t, err)
+	assert.Equal(t, []Candidate{{Original, "\xff"}}, forms)
+
+	forms, err = Forms("\xff", Replace)
+	require.NoError(t, err)
+	assert.Equal(t, []Candidate{{NFKC, "\ufffd"}, {Original, "\xff"}}, forms)
+
+	_, err = Forms("\xff", Reject)
+	assert.Equal(t, ErrInvalidUTF8, err)
+}
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+
+	expanded, err := Expand([]string{"password", "cafe\u0301", "ｐａｓｓ"}, Reject)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"password", "cafe\u0301", "caf\u00e9", "ｐａｓｓ", "pass"}, expanded)
+
+	_, err = Expand([]string{"password", "\xff"}, Reject)
+	assert.Equal(t, ErrInvalidUTF8, err)
+}
@@ -0,0 +1,382 @@
+// Package override provides a pwned.Ranger that removes
+// entries from, or overrides the counts of entries in, the
+// results of another Ranger.
+//
+// The overrides are loaded from a file where each line is
+// the hexadecimal SHA1 digest of a password, optionally
+// followed by a ':' and a count, as in:
+//  # randomly generated secret, see INC-1234
+//  5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+//  7C4A8D09CA3762AF61E59520943DC26494F8941B:1
+// A digest without a count, or with a count of zero, is
+// removed from the results. Otherwise the count replaces
+// the count returned by the underlying Ranger. If a digest
+// appears more than once, the last line for it is used.
+// Blank lines and lines starting with '#' are ignored.
+package override
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"go.tmthrgd.dev/pwned/internal/filewatch"
+)
+
+// Event records an override that was applied to a
+// response.
+type Event struct {
+	// Digest is the SHA1 digest that was overridden.
+	Digest [sha1.Size]byte
+	// Original is the count returned by the underlying
+	// Ranger, or zero if it was not present.
+	Original int
+	// Count is the count that was returned instead, or
+	// zero if it was removed.
+	Count int
+}
+
+type entry struct {
+	suffix [pwned.SuffixSize]byte
+	count  uint64
+}
+
+// Ranger is a pwned.Ranger that applies overrides to the
+// results of another Ranger. It also implements
+// pwnedgrpc.Lookup, using the underlying Ranger's Lookup
+// method if it has one.
+//
+// A Ranger is safe for concurrent use.
+type Ranger struct {
+	ranger pwned.Ranger
+	lookup pwnedgrpc.Lookup
+	path   string
+
+	watch    time.Duration
+	watcher  *filewatch.Watcher
+	audit    func(ctx context.Context, e Event)
+	reloaded func(overrides int, err error)
+
+	mu        sync.RWMutex
+	overrides map[string][]entry
+}
+
+// New returns a Ranger that applies the overrides in the
+// file at path to the results of r.
+func New(r pwned.Ranger, path string, opts ...Option) (*Ranger, error) {
+	lookup, _ := r.(pwnedgrpc.Lookup)
+	o := &Ranger{
+		ranger: r,
+		lookup: lookup,
+		path:   path,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.watch > 0 {
+		o.watcher = filewatch.Watch(path, o.watch, func() { o.Reload() })
+	}
+
+	overrides, err := load(path)
+	if err != nil {
+		o.Close()
+		return nil, err
+	}
+
+	o.mu.Lock()
+	o.overrides = overrides
+	o.mu.Unlock()
+	return o, nil
+}
+
+// Close stops watching the overrides file for changes. It
+// does not close the underlying Ranger.
+func (o *Ranger) Close() error {
+	if o.watcher != nil {
+		o.watcher.Stop()
+	}
+
+	return nil
+}
+
+// Reload reads the overrides from the file again. If the
+// file cannot be read or parsed, the existing overrides
+// continue to be used.
+func (o *Ranger) Reload() error {
+	overrides, err := load(o.path)
+	if err == nil {
+		o.mu.Lock()
+		o.overrides = overrides
+		o.mu.Unlock()
+	}
+
+	if o.reloaded != nil {
+		var n int
+		for _, entries := range overrides {
+			n += len(entries)
+		}
+
+		o.reloaded(n, err)
+	}
+
+	return err
+}
+
+func load(path string) (map[string][]entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/override: failed to open overrides: %v", err)
+	}
+	defer f.Close()
+
+	// A later line for the same digest replaces an earlier
+	// one.
+	counts := make(map[[sha1.Size]byte]uint64)
+
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		hash, count := text, uint64(0)
+		if idx := strings.IndexByte(text, ':'); idx >= 0 {
+			hash = text[:idx]
+
+			if count, err = strconv.ParseUint(strings.TrimSpace(text[idx+1:]), 10, 64); err != nil {
+				return nil, fmt.Errorf("pwned/override: invalid count on line %d", line)
+			}
+		}
+
+		var digest [sha1.Size]byte
+		if len(hash) != hex.EncodedLen(sha1.Size) {
+			return nil, fmt.Errorf("pwned/override: invalid digest on line %d", line)
+		}
+		if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+			return nil, fmt.Errorf("pwned/override: invalid digest on line %d", line)
+		}
+
+		counts[digest] = count
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("pwned/override: failed to read overrides: %v", err)
+	}
+
+	overrides := make(map[string][]entry)
+	for digest, count := range counts {
+		prefix, suffix := pwned.SplitDigest(digest)
+		overrides[prefix] = append(overrides[prefix], entry{suffix, count})
+	}
+
+	// The entries are kept sorted by suffix so that they
+	// can be merged into sorted results, and searched.
+	for _, entries := range overrides {
+		sort.Slice(entries, func(i, j int) bool {
+			return string(entries[i].suffix[:]) < string(entries[j].suffix[:])
+		})
+	}
+
+	return overrides, nil
+}
+
+func (o *Ranger) entries(prefix string) []entry {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.overrides[strings.ToLower(prefix)]
+}
+
+// Range implements pwned.Ranger.
+func (o *Ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	res, err := o.ranger.Range(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := o.entries(prefix)
+	if len(entries) == 0 {
+		return res, nil
+	}
+
+	if len(res)%(pwned.SuffixSize+1) != 0 {
+		return nil, fmt.Errorf("pwned/override: invalid result set returned")
+	}
+
+	// The first pass finds the count each entry overrides,
+	// so that an entry can be placed before the results
+	// that follow it, even if it appears later in res.
+	original := make([]int, len(entries))
+	present := make([]bool, len(entries))
+	for set := res; len(set) > 0; set = set[pwned.SuffixSize+1:] {
+		var suffix [pwned.SuffixSize]byte
+		copy(suffix[:], set)
+
+		if i := find(entries, suffix); i >= 0 {
+			present[i] = true
+			original[i] = pwned.SearchSet(set[:pwned.SuffixSize+1], suffix)
+		}
+	}
+
+	// The second pass merges the entries into the results
+	// in sorted position, assuming res is sorted as range
+	// queries to the Pwned Passwords API are.
+	var next int
+	set := make([]byte, 0, len(res)+pwned.Size(len(entries)))
+	for ; len(res) > 0; res = res[pwned.SuffixSize+1:] {
+		var suffix [pwned.SuffixSize]byte
+		copy(suffix[:], res)
+
+		for ; next < len(entries) && string(entries[next].suffix[:]) < string(suffix[:]); next++ {
+			if !present[next] {
+				set = o.apply(ctx, set, prefix, entries[next], 0)
+			}
+		}
+
+		i := find(entries, suffix)
+		if i < 0 {
+			set = append(set, res[:pwned.SuffixSize+1]...)
+			continue
+		}
+
+		set = o.apply(ctx, set, prefix, entries[i], original[i])
+	}
+
+	for ; next < len(entries); next++ {
+		if !present[next] {
+			set = o.apply(ctx, set, prefix, entries[next], 0)
+		}
+	}
+
+	return set, nil
+}
+
+func (o *Ranger) apply(ctx context.Context, set []byte, prefix string, e entry, original int) []byte {
+	if e.count != 0 {
+		set = pwned.AppendResult(set, e.suffix, e.count)
+	}
+
+	count := estimate(e.suffix, e.count)
+	if o.audit != nil && count != original {
+		o.audit(ctx, Event{
+			Digest:   digest(prefix, e.suffix),
+			Original: original,
+			Count:    count,
+		})
+	}
+
+	return set
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (o *Ranger) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	if o.lookup != nil {
+		count, err = o.lookup.Lookup(ctx, digest)
+	} else {
+		var res []byte
+		if res, err = o.ranger.Range(ctx, prefix); err == nil {
+			if len(res)%(pwned.SuffixSize+1) != 0 {
+				return 0, fmt.Errorf("pwned/override: invalid result set returned")
+			}
+
+			count = pwned.SearchSet(res, suffix)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	entries := o.entries(prefix)
+
+	i := find(entries, suffix)
+	if i < 0 {
+		return count, nil
+	}
+
+	original := count
+	count = estimate(suffix, entries[i].count)
+
+	if o.audit != nil && count != original {
+		o.audit(ctx, Event{
+			Digest:   digest,
+			Original: original,
+			Count:    count,
+		})
+	}
+
+	return count, nil
+}
+
+// find returns the index of suffix in entries, which must
+// be sorted, or -1 if it is not present.
+func find(entries []entry, suffix [pwned.SuffixSize]byte) int {
+	i := sort.Search(len(entries), func(i int) bool {
+		return string(entries[i].suffix[:]) >= string(suffix[:])
+	})
+	if i < len(entries) && entries[i].suffix == suffix {
+		return i
+	}
+
+	return -1
+}
+
+// estimate returns the count that a client would see for
+// an entry with the given count.
+func estimate(suffix [pwned.SuffixSize]byte, count uint64) int {
+	if count == 0 {
+		return 0
+	}
+
+	return pwned.SearchSet(pwned.AppendResult(nil, suffix, count), suffix)
+}
+
+func digest(prefix string, suffix [pwned.SuffixSize]byte) (digest [sha1.Size]byte) {
+	hex.Decode(digest[:pwned.PrefixSize/2], []byte(prefix[:pwned.PrefixSize-1]))
+	copy(digest[pwned.PrefixSize/2:], suffix[:])
+	return digest
+}
+
+// Option allows the behaviour of the Ranger to be
+// configured.
+type Option func(*Ranger)
+
+// WithWatch causes the Ranger to check whether the
+// overrides file has changed once per interval, and reload
+// it if it has. The check is performed in the background
+// until Close is called.
+func WithWatch(interval time.Duration) Option {
+	return func(o *Ranger) {
+		o.watch = interval
+	}
+}
+
+// WithAudit sets a function that is called whenever an
+// override changes the count of an entry in a response.
+func WithAudit(fn func(ctx context.Context, e Event)) Option {
+	return func(o *Ranger) {
+		o.audit = fn
+	}
+}
+
+// WithReloadHook sets a function that is called whenever
+// the overrides are reloaded, with the number of overrides
+// loaded or the error that prevented them from loading.
+func WithReloadHook(fn func(overrides int, err error)) Option {
+	return func(o *Ranger) {
+		o.reloaded = fn
+	}
+}
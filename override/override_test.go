@@ -0,0 +1,230 @@
+package override
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+)
+
+type ranger map[string][]byte
+
+func newRanger(counts map[string]uint64) ranger {
+	r := make(ranger)
+	for password, count := range counts {
+		prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte(password)))
+		r[prefix] = pwned.AppendResult(r[prefix], suffix, count)
+	}
+
+	return r
+}
+
+func (r ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return r[prefix], nil
+}
+
+func hash(password string) string {
+	digest := sha1.Sum([]byte(password))
+	return hex.EncodeToString(digest[:])
+}
+
+func search(t *testing.T, r *Ranger, password string) int {
+	digest := sha1.Sum([]byte(password))
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	set, err := r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+
+	count, err := r.Lookup(context.Background(), digest)
+	require.NoError(t, err)
+	assert.Equal(t, pwned.SearchSet(set, suffix), count)
+
+	return count
+}
+
+func writeOverrides(t *testing.T, path, data string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func TestOverride(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-override")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "overrides.txt")
+	writeOverrides(t, path, "# comment\n\n"+
+		hash("password")+"\n"+
+		hash("P@ssw0rd")+":1\n"+
+		hash("melobie")+":32\n")
+
+	var (
+		mu     sync.Mutex
+		events []Event
+	)
+	r, err := New(newRanger(map[string]uint64{
+		"password": 8,
+		"P@ssw0rd": 4,
+		"lauragpe": 2,
+	}), path, WithAudit(func(ctx context.Context, e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, search(t, r, "password"))
+	assert.Equal(t, 1, search(t, r, "P@ssw0rd"))
+	assert.Equal(t, 2, search(t, r, "lauragpe"))
+	assert.Equal(t, 32, search(t, r, "melobie"))
+
+	assert.Contains(t, events, Event{sha1.Sum([]byte("password")), 8, 0})
+	assert.Contains(t, events, Event{sha1.Sum([]byte("P@ssw0rd")), 4, 1})
+	assert.Contains(t, events, Event{sha1.Sum([]byte("melobie")), 0, 32})
+
+	writeOverrides(t, path, hash("lauragpe")+"\n")
+	require.NoError(t, r.Reload())
+
+	assert.Equal(t, 8, search(t, r, "password"))
+	assert.Equal(t, 0, search(t, r, "lauragpe"))
+
+	writeOverrides(t, path, "not a hash\n")
+	assert.Error(t, r.Reload())
+	assert.Equal(t, 0, search(t, r, "lauragpe"))
+}
+
+func TestOverrideWatch(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-override")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "overrides.txt")
+	writeOverrides(t, path, hash("password")+"\n")
+
+	reloaded := make(chan int, 1)
+	r, err := New(newRanger(map[string]uint64{"password": 8}), path,
+		WithWatch(time.Millisecond),
+		WithReloadHook(func(n int, err error) {
+			assert.NoError(t, err)
+			reloaded <- n
+		}))
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Equal(t, 0, search(t, r, "password"))
+
+	require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+
+	select {
+	case n := <-reloaded:
+		assert.Equal(t, 0, n)
+	case <-time.After(10 * time.Second):
+		t.Fatal("overrides were not reloaded")
+	}
+
+	assert.Equal(t, 8, search(t, r, "password"))
+}
+
+func TestOverrideDuplicates(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-override")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "overrides.txt")
+	writeOverrides(t, path, hash("password")+":4\n"+
+		hash("P@ssw0rd")+"\n"+
+		hash("password")+":16\n"+
+		hash("P@ssw0rd")+":2\n")
+
+	var n int
+	r, err := New(newRanger(map[string]uint64{"password": 8}), path,
+		WithReloadHook(func(overrides int, err error) { n = overrides }))
+	require.NoError(t, err)
+
+	assert.Equal(t, 16, search(t, r, "password"))
+	assert.Equal(t, 2, search(t, r, "P@ssw0rd"))
+
+	set, err := r.Range(context.Background(), "5baa6")
+	require.NoError(t, err)
+	assert.Len(t, set, pwned.Size(1))
+
+	require.NoError(t, r.Reload())
+	assert.Equal(t, 2, n)
+}
+
+func TestOverrideInvalid(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-override")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "overrides.txt")
+	writeOverrides(t, path, "")
+
+	r, err := New(ranger{"5baa6": []byte("short")}, path)
+	require.NoError(t, err)
+
+	_, err = r.Lookup(context.Background(), sha1.Sum([]byte("password")))
+	assert.Error(t, err)
+}
+
+func TestOverrideSorted(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-override")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	digest := func(k byte) (d [sha1.Size]byte) {
+		d[2] = k
+		return d
+	}
+
+	var set []byte
+	for _, k := range []byte{2, 4} {
+		_, suffix := pwned.SplitDigest(digest(k))
+		set = pwned.AppendResult(set, suffix, 2)
+	}
+
+	var lines string
+	for _, line := range []struct {
+		k     byte
+		count string
+	}{{5, ":8"}, {3, ":4"}, {4, ""}, {1, ":8"}} {
+		d := digest(line.k)
+		lines += hex.EncodeToString(d[:]) + line.count + "\n"
+	}
+
+	path := filepath.Join(dir, "overrides.txt")
+	writeOverrides(t, path, lines)
+
+	r, err := New(ranger{"00000": set}, path)
+	require.NoError(t, err)
+
+	res, err := r.Range(context.Background(), "00000")
+	require.NoError(t, err)
+
+	var got []byte
+	for ; len(res) > 0; res = res[pwned.SuffixSize+1:] {
+		got = append(got, res[0])
+	}
+	assert.Equal(t, []byte{1, 2, 3, 5}, got)
+}
@@ -2,14 +2,14 @@ package passwords
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/sha1"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 
 	"go.tmthrgd.dev/pwned"
-	"go4.org/strutil"
 )
 
 // Each line is:
@@ -21,55 +21,108 @@ import (
 // This gives a maximum length of 63-bytes per-line,
 // allow some further overhead.
 const (
-	lineBufSize = 64
+	readBufSize = 4096
 	maxLineSize = 96
 )
 
+// maxEmptyReads is the number of consecutive empty reads
+// that are allowed before giving up, as in bufio.Scanner.
+const maxEmptyReads = 100
+
 // Reader parses either the raw Pwned Passwords dataset or
 // a range query from the ‘Have I been pwned?’ APIv2.
 type Reader struct {
-	s   *bufio.Scanner
+	rd  io.Reader
 	err error
+	eof bool
+
+	// buf[r:w] holds the data that has been read but
+	// not yet parsed.
+	buf  []byte
+	r, w int
 
 	count  uint64
 	prefix string
 	suffix [pwned.SuffixSize]byte
 
-	hexBuf [2 * pwned.SuffixSize]byte
-
 	dataset bool
+
+	// size is the length of the hashes in bytes.
+	size int
 }
 
+// ntlmSize is the size of an NTLM hash.
+const ntlmSize = 16
+
 // NewDatasetReader parses the Pwned Passwords list from
 // https://haveibeenpwned.com. The provided io.Reader should
 // represent pwned-passwords-2.0.txt.
 //
 // See https://haveibeenpwned.com/Passwords.
 func NewDatasetReader(r io.Reader) *Reader {
-	s := bufio.NewScanner(r)
-	s.Buffer(make([]byte, lineBufSize), maxLineSize)
-
 	return &Reader{
-		s: s,
+		rd:  r,
+		buf: make([]byte, readBufSize),
 
 		dataset: true,
+		size:    sha1.Size,
 	}
 }
 
+// NewNTLMDatasetReader is like NewDatasetReader but parses
+// the NTLM variant of the Pwned Passwords list.
+//
+// NTLM hashes are shorter than SHA1 hashes. The suffixes
+// returned by Entry are padded with zeros to
+// pwned.SuffixSize so they can be used with the rest of
+// the pwned package.
+func NewNTLMDatasetReader(r io.Reader) *Reader {
+	rd := NewDatasetReader(r)
+	rd.size = ntlmSize
+	return rd
+}
+
 // NewResultsReader parses the result from a ‘Have I been
 // pwned?’ APIv2 range query.
 //
 // See https://haveibeenpwned.com/API/v2#PwnedPasswords.
 func NewResultsReader(r io.Reader, prefix string) *Reader {
-	s := bufio.NewScanner(r)
-	s.Buffer(make([]byte, lineBufSize), maxLineSize)
-
 	return &Reader{
-		s: s,
+		rd:  r,
+		buf: make([]byte, readBufSize),
 
 		prefix: prefix,
 
 		dataset: false,
+		size:    sha1.Size,
+	}
+}
+
+// NewNTLMResultsReader is like NewResultsReader but parses
+// the result of an NTLM range query, as made with
+// mode=ntlm. The suffixes are padded as described by
+// NewNTLMDatasetReader.
+func NewNTLMResultsReader(r io.Reader, prefix string) *Reader {
+	rd := NewResultsReader(r, prefix)
+	rd.size = ntlmSize
+	return rd
+}
+
+// Reset discards any state and switches the Reader to
+// read from r, reusing its buffer. prefix is used by
+// readers created with NewResultsReader and is ignored
+// otherwise.
+func (r *Reader) Reset(rd io.Reader, prefix string) {
+	*r = Reader{
+		rd:  rd,
+		buf: r.buf,
+
+		dataset: r.dataset,
+		size:    r.size,
+	}
+
+	if !r.dataset {
+		r.prefix = prefix
 	}
 }
 
@@ -81,11 +134,14 @@ func NewResultsReader(r io.Reader, prefix string) *Reader {
 // scanning, except that if it was io.EOF, Err will return
 // nil.
 func (r *Reader) Scan() bool {
-	if r.err != nil || !r.s.Scan() {
+	if r.err != nil {
 		return false
 	}
 
-	line := r.s.Bytes()
+	line, ok := r.readLine()
+	if !ok {
+		return false
+	}
 
 	if r.dataset {
 		if len(line) < 5 {
@@ -93,19 +149,23 @@ func (r *Reader) Scan() bool {
 			return false
 		}
 
-		r.prefix, line = string(line[:5]), line[5:]
+		// Consecutive entries usually share a prefix, so
+		// only allocate a new string when it changes.
+		if r.prefix != string(line[:5]) {
+			r.prefix = string(line[:5])
+		}
+
+		line = line[5:]
 	}
 
-	const suffixSize = 2*sha1.Size - 5
+	suffixSize := 2*r.size - 5
 	if len(line) < suffixSize+1 {
 		r.err = errors.New("pwned: truncated data")
 		return false
 	}
 
-	r.hexBuf[0] = r.prefix[4]
-	copy(r.hexBuf[1:], line[:suffixSize])
-
-	if _, r.err = hex.Decode(r.suffix[:], r.hexBuf[:]); r.err != nil {
+	if !decodeSuffix(r.suffix[:r.size-2], r.prefix[4], line[:suffixSize]) {
+		r.err = errors.New("pwned: invalid hex in data")
 		return false
 	}
 
@@ -115,8 +175,129 @@ func (r *Reader) Scan() bool {
 		return false
 	}
 
-	r.count, r.err = strutil.ParseUintBytes(line, 10, 64)
-	return r.err == nil
+	if r.count, ok = parseCount(line); !ok {
+		r.err = fmt.Errorf("pwned: invalid count %q", line)
+		return false
+	}
+
+	return true
+}
+
+// readLine returns the next line, without its line ending,
+// in the same way as bufio.ScanLines. The line is only
+// valid until the next call.
+func (r *Reader) readLine() ([]byte, bool) {
+	for empty := 0; ; {
+		if i := bytes.IndexByte(r.buf[r.r:r.w], '\n'); i >= 0 {
+			line := r.buf[r.r : r.r+i]
+			r.r += i + 1
+			return dropCR(line), true
+		}
+
+		if r.w-r.r >= maxLineSize {
+			r.err = bufio.ErrTooLong
+			return nil, false
+		}
+
+		if r.eof {
+			if r.r == r.w {
+				return nil, false
+			}
+
+			line := r.buf[r.r:r.w]
+			r.r = r.w
+			return dropCR(line), true
+		}
+
+		if r.r > 0 {
+			r.w = copy(r.buf, r.buf[r.r:r.w])
+			r.r = 0
+		}
+
+		n, err := r.rd.Read(r.buf[r.w:])
+		r.w += n
+
+		switch {
+		case err == io.EOF:
+			r.eof = true
+		case err != nil:
+			r.err = err
+			return nil, false
+		case n == 0:
+			if empty++; empty >= maxEmptyReads {
+				r.err = io.ErrNoProgress
+				return nil, false
+			}
+		default:
+			empty = 0
+		}
+	}
+}
+
+func dropCR(line []byte) []byte {
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		return line[:len(line)-1]
+	}
+
+	return line
+}
+
+// hexTable maps each hexadecimal character to its value and
+// every other byte to 0xff.
+var hexTable = func() (t [256]byte) {
+	for i := range t {
+		t[i] = 0xff
+	}
+
+	for c := '0'; c <= '9'; c++ {
+		t[c] = byte(c - '0')
+	}
+
+	for c := 'a'; c <= 'f'; c++ {
+		t[c] = byte(c-'a') + 10
+		t[c-'a'+'A'] = byte(c-'a') + 10
+	}
+
+	return t
+}()
+
+// decodeSuffix decodes the hex encoded suffix, whose first
+// character is the last character of the prefix.
+func decodeSuffix(dst []byte, last byte, src []byte) bool {
+	hi, lo := hexTable[last], hexTable[src[0]]
+	invalid := hi | lo
+	dst[0] = hi<<4 | lo
+
+	for i := 1; i < len(dst); i++ {
+		hi, lo := hexTable[src[2*i-1]], hexTable[src[2*i]]
+		invalid |= hi | lo
+		dst[i] = hi<<4 | lo
+	}
+
+	return invalid&0xf0 == 0
+}
+
+// parseCount parses a decimal count without allocating.
+func parseCount(b []byte) (uint64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+
+		d := uint64(c - '0')
+		if n > (math.MaxUint64-d)/10 {
+			return 0, false
+		}
+
+		n = n*10 + d
+	}
+
+	return n, true
 }
 
 // Entry returns the most recent entry generated by a call
@@ -128,9 +309,5 @@ func (r *Reader) Entry() (prefix string, suffix [pwned.SuffixSize]byte, count ui
 // Err returns the first non-EOF error that was encountered
 // by the Reader.
 func (r *Reader) Err() error {
-	if r.err != nil {
-		return r.err
-	}
-
-	return r.s.Err()
+	return r.err
 }
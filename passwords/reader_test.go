@@ -0,0 +1,228 @@
+package passwords
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+)
+
+func TestDatasetReader(t *testing.T) {
+	t.Parallel()
+
+	data := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3730471\r\n" +
+		"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd9:1\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:18446744073709551615"
+
+	for _, rd := range []io.Reader{
+		strings.NewReader(data),
+		iotest.OneByteReader(strings.NewReader(data)),
+		iotest.DataErrReader(strings.NewReader(data)),
+	} {
+		r := NewDatasetReader(rd)
+
+		var counts []uint64
+		for r.Scan() {
+			prefix, suffix, count := r.Entry()
+			counts = append(counts, count)
+
+			if len(counts) == 1 {
+				assert.Equal(t, "5BAA6", prefix)
+				assert.Equal(t, "1e4c9b93f3f0682250b6cf8331b7ee68fd8", hex.EncodeToString(suffix[:])[1:])
+			}
+		}
+
+		require.NoError(t, r.Err())
+		assert.Equal(t, []uint64{3730471, 1, 1<<64 - 1}, counts)
+	}
+}
+
+func TestResultsReader(t *testing.T) {
+	t.Parallel()
+
+	digest := sha1.Sum([]byte("password"))
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	r := NewResultsReader(strings.NewReader("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+
+		strings.ToUpper(hex.EncodeToString(digest[:]))[5:]+":3730471\r\n"), prefix)
+
+	require.True(t, r.Scan())
+	require.True(t, r.Scan())
+
+	pfx, sfx, count := r.Entry()
+	assert.Equal(t, prefix, pfx)
+	assert.Equal(t, suffix, sfx)
+	assert.Equal(t, uint64(3730471), count)
+
+	assert.False(t, r.Scan())
+	assert.NoError(t, r.Err())
+
+	r.Reset(strings.NewReader("0018A45C4D1DEF81644B54AB7F969B88D65:2\n"), "00000")
+	require.True(t, r.Scan())
+
+	pfx, _, count = r.Entry()
+	assert.Equal(t, "00000", pfx)
+	assert.Equal(t, uint64(2), count)
+}
+
+func TestNTLMResultsReader(t *testing.T) {
+	t.Parallel()
+
+	var digest [sha1.Size]byte
+	hex.Decode(digest[:], []byte("8846F7EAEE8FB117AD06BDD830B7586C")) // NTLM of "password"
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	r := NewNTLMResultsReader(strings.NewReader("0013A5BA7AF1F5C0ABC64A78F29:4\r\n"+
+		"7EAEE8FB117AD06BDD830B7586C:9545824\r\n"), prefix)
+
+	require.True(t, r.Scan())
+	require.True(t, r.Scan())
+
+	pfx, sfx, count := r.Entry()
+	assert.Equal(t, prefix, pfx)
+	assert.Equal(t, suffix, sfx)
+	assert.Equal(t, uint64(9545824), count)
+
+	assert.False(t, r.Scan())
+	assert.NoError(t, r.Err())
+
+	r = NewNTLMResultsReader(strings.NewReader("1E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n"), prefix)
+	assert.False(t, r.Scan())
+	assert.Error(t, r.Err())
+}
+
+func TestReaderInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8-1",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1a",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:18446744073709551616",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FDG:1",
+		"5BAAG1E4C9B93F3F0682250B6CF8331B7EE68FD8:1",
+		"5BAA6",
+		"\n",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:" + strings.Repeat("0", 100),
+	} {
+		r := NewDatasetReader(strings.NewReader(data))
+		assert.False(t, r.Scan(), data)
+		assert.Error(t, r.Err(), data)
+	}
+
+	r := NewDatasetReader(iotest.TimeoutReader(strings.NewReader(strings.Repeat("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n", 200))))
+	for r.Scan() {
+	}
+	assert.Equal(t, iotest.ErrTimeout, r.Err())
+}
+
+// benchmarkDataset returns n random entries ordered by
+// hash, as in the Pwned Passwords list.
+func benchmarkDataset(n int) []byte {
+	rand := rand.New(rand.NewSource(0))
+
+	lines := make([]string, n)
+	for i := range lines {
+		var digest [sha1.Size]byte
+		rand.Read(digest[:])
+		lines[i] = fmt.Sprintf("%s:%d\r\n", strings.ToUpper(hex.EncodeToString(digest[:])), rand.Intn(1e6)+1)
+	}
+
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, ""))
+}
+
+func BenchmarkReader(b *testing.B) {
+	data := benchmarkDataset(10000)
+
+	b.Run("Reader", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			r := NewDatasetReader(bytes.NewReader(data))
+			for r.Scan() {
+			}
+			if r.Err() != nil {
+				b.Fatal(r.Err())
+			}
+		}
+	})
+
+	// Scanner is the bufio.Scanner and hex.Decode based
+	// parser that Reader replaced, for comparison.
+	b.Run("Scanner", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			s := bufio.NewScanner(bytes.NewReader(data))
+			s.Buffer(make([]byte, 64), maxLineSize)
+
+			var (
+				prefix string
+				hexBuf [2 * pwned.SuffixSize]byte
+				suffix [pwned.SuffixSize]byte
+			)
+			for s.Scan() {
+				line := s.Bytes()
+				prefix = string(line[:5])
+
+				hexBuf[0] = prefix[4]
+				copy(hexBuf[1:], line[5:40])
+				if _, err := hex.Decode(suffix[:], hexBuf[:]); err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := strconv.ParseUint(string(line[41:]), 10, 64); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			_ = prefix
+		}
+	})
+}
+
+func BenchmarkResultsReader(b *testing.B) {
+	const average = 478
+	data := benchmarkDataset(average)
+
+	var results bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) > 5 {
+			results.Write(line[5:])
+		}
+	}
+
+	b.SetBytes(int64(results.Len()))
+	b.ReportAllocs()
+
+	r := NewResultsReader(nil, "")
+	rd := bytes.NewReader(nil)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		rd.Reset(results.Bytes())
+		r.Reset(rd, "5BAA6")
+		for r.Scan() {
+		}
+		if r.Err() != nil {
+			b.Fatal(r.Err())
+		}
+	}
+}
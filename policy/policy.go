@@ -0,0 +1,380 @@
+// Package policy evaluates candidate passwords against the
+// rules recommended by NIST SP 800-63B, including checking
+// them against a pwned password list.
+//
+// See https://pages.nist.gov/800-63-3/sp800-63b.html#memsecret.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"google.golang.org/grpc"
+)
+
+// Rule identifies the rule that a password violates.
+type Rule string
+
+// The rules a password is evaluated against.
+const (
+	TooShort    Rule = "too-short"
+	TooLong     Rule = "too-long"
+	Repetitive  Rule = "repetitive"
+	Sequential  Rule = "sequential"
+	ContextWord Rule = "context-word"
+	Breached    Rule = "breached"
+
+	// BreachedVariant is only checked when WithVariants is
+	// used.
+	BreachedVariant Rule = "breached-variant"
+)
+
+// Violation is a rule that a password violates. Message
+// describes the violation and never includes the password.
+type Violation struct {
+	Rule    Rule   `json:"rule"`
+	Message string `json:"message"`
+	// Count is the number of times the password, or its
+	// variant, has been seen, for Breached and
+	// BreachedVariant violations.
+	Count int `json:"count,omitempty"`
+}
+
+func (v Violation) String() string {
+	return v.Message
+}
+
+// The defaults used by New.
+const (
+	// DefaultMinLength is the minimum length required of
+	// user chosen passwords by NIST SP 800-63B.
+	DefaultMinLength = 8
+	// DefaultMaxLength is the smallest maximum length
+	// permitted by NIST SP 800-63B.
+	DefaultMaxLength = 64
+	// DefaultMaxRepeat is the longest permitted run of a
+	// repeated character, as in "aaa".
+	DefaultMaxRepeat = 3
+	// DefaultMaxSequence is the longest permitted run of
+	// sequential characters, as in "abc" or "321".
+	DefaultMaxSequence = 3
+	// DefaultBreachThreshold rejects any password that has
+	// been seen in a breach.
+	DefaultBreachThreshold = 1
+	// DefaultConcurrency is the number of prefixes that
+	// are queried concurrently when checking variants.
+	DefaultConcurrency = 4
+)
+
+// minContextWord is the shortest context word that is
+// checked. Shorter words would reject too many passwords.
+const minContextWord = 3
+
+// Policy evaluates passwords.
+type Policy struct {
+	minLength, maxLength int
+	maxRepeat            int
+	maxSequence          int
+	contextWords         []string
+
+	search      func(ctx context.Context, password string) (int, error)
+	src         pwned.Source
+	threshold   int
+	variants    bool
+	concurrency int
+}
+
+// New returns a Policy with the given options. By default
+// it uses the Default* constants and does not check
+// passwords against a pwned password list; use WithRanger
+// or WithClient to enable that.
+func New(opts ...Option) *Policy {
+	p := &Policy{
+		minLength:   DefaultMinLength,
+		maxLength:   DefaultMaxLength,
+		maxRepeat:   DefaultMaxRepeat,
+		maxSequence: DefaultMaxSequence,
+
+		threshold:   DefaultBreachThreshold,
+		concurrency: DefaultConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Check evaluates password and returns the rules it
+// violates, or nil if it is acceptable. contextWords are
+// added to those given to WithContextWords, and would
+// typically include the user's name or email address.
+//
+// Lengths are counted in Unicode code points. An error is
+// only returned if the pwned password list could not be
+// queried.
+func (p *Policy) Check(ctx context.Context, password string, contextWords ...string) ([]Violation, error) {
+	var violations []Violation
+
+	switch n := utf8.RuneCountInString(password); {
+	case n < p.minLength:
+		violations = append(violations, Violation{
+			Rule:    TooShort,
+			Message: fmt.Sprintf("password must be at least %d characters", p.minLength),
+		})
+	case p.maxLength > 0 && n > p.maxLength:
+		violations = append(violations, Violation{
+			Rule:    TooLong,
+			Message: fmt.Sprintf("password must be at most %d characters", p.maxLength),
+		})
+	}
+
+	if p.maxRepeat > 0 && longestRepeat(password) > p.maxRepeat {
+		violations = append(violations, Violation{
+			Rule:    Repetitive,
+			Message: fmt.Sprintf("password must not repeat a character more than %d times in a row", p.maxRepeat),
+		})
+	}
+
+	if p.maxSequence > 0 && longestSequence(password) > p.maxSequence {
+		violations = append(violations, Violation{
+			Rule:    Sequential,
+			Message: fmt.Sprintf("password must not contain more than %d sequential characters", p.maxSequence),
+		})
+	}
+
+	if word, ok := p.containsContextWord(password, contextWords); ok {
+		violations = append(violations, Violation{
+			Rule:    ContextWord,
+			Message: fmt.Sprintf("password must not contain %q", word),
+		})
+	}
+
+	if p.search != nil {
+		count, err := p.search(ctx, password)
+		if err != nil {
+			return nil, err
+		}
+
+		if count >= p.threshold {
+			violations = append(violations, Violation{
+				Rule:    Breached,
+				Message: "password has appeared in a data breach",
+				Count:   count,
+			})
+		}
+	}
+
+	if p.variants && p.src != nil {
+		count, err := p.checkVariants(ctx, password)
+		if err != nil {
+			return nil, err
+		}
+
+		if count >= p.threshold {
+			violations = append(violations, Violation{
+				Rule:    BreachedVariant,
+				Message: "password is a variant of a password that has appeared in a data breach",
+				Count:   count,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+func (p *Policy) containsContextWord(password string, extra []string) (string, bool) {
+	lower := strings.ToLower(password)
+	for _, words := range [][]string{p.contextWords, extra} {
+		for _, word := range words {
+			if utf8.RuneCountInString(word) < minContextWord {
+				continue
+			}
+
+			if strings.Contains(lower, strings.ToLower(word)) {
+				return word, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// longestRepeat returns the length of the longest run of a
+// repeated character in s, ignoring case.
+func longestRepeat(s string) int {
+	var longest, run int
+	var prev rune
+	for _, r := range s {
+		r = unicode.ToLower(r)
+
+		if run > 0 && r == prev {
+			run++
+		} else {
+			run = 1
+		}
+
+		if run > longest {
+			longest = run
+		}
+
+		prev = r
+	}
+
+	return longest
+}
+
+// longestSequence returns the length of the longest run of
+// ascending or descending letters or digits in s, such as
+// "abcd" or "4321", ignoring case.
+func longestSequence(s string) int {
+	var longest, run int
+	var prev, step rune
+	for _, r := range s {
+		r = unicode.ToLower(r)
+
+		d := r - prev
+		switch {
+		case run == 0 || !isAlnum(r) || !isAlnum(prev) || (d != 1 && d != -1):
+			run = 1
+		case run == 1 || d == step:
+			run++
+		default:
+			// The direction changed, as in "abcba".
+			run = 2
+		}
+
+		if run > longest {
+			longest = run
+		}
+
+		prev, step = r, d
+	}
+
+	return longest
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Option allows the behaviour of the Policy to be
+// configured.
+type Option func(*Policy)
+
+// WithMinLength sets the minimum length of a password. It
+// defaults to DefaultMinLength.
+func WithMinLength(n int) Option {
+	return func(p *Policy) {
+		p.minLength = n
+	}
+}
+
+// WithMaxLength sets the maximum length of a password. It
+// defaults to DefaultMaxLength. NIST SP 800-63B requires
+// that it be at least 64, or zero for no maximum.
+func WithMaxLength(n int) Option {
+	if n != 0 && n < DefaultMaxLength {
+		panic("pwned/policy: maximum length must be at least 64")
+	}
+
+	return func(p *Policy) {
+		p.maxLength = n
+	}
+}
+
+// WithMaxRepeat sets the longest permitted run of a
+// repeated character. It defaults to DefaultMaxRepeat.
+// Zero disables the rule.
+func WithMaxRepeat(n int) Option {
+	return func(p *Policy) {
+		p.maxRepeat = n
+	}
+}
+
+// WithMaxSequence sets the longest permitted run of
+// sequential characters. It defaults to
+// DefaultMaxSequence. Zero disables the rule.
+func WithMaxSequence(n int) Option {
+	return func(p *Policy) {
+		p.maxSequence = n
+	}
+}
+
+// WithContextWords sets words that passwords must not
+// contain, such as the name of the service. Words are
+// matched without regard to case and words shorter than
+// three characters are ignored.
+func WithContextWords(words ...string) Option {
+	return func(p *Policy) {
+		p.contextWords = append(p.contextWords, words...)
+	}
+}
+
+// WithRanger checks passwords against r with pwned.Search.
+func WithRanger(r pwned.Ranger) Option {
+	return func(p *Policy) {
+		p.search = func(ctx context.Context, password string) (int, error) {
+			return pwned.Search(ctx, r, password)
+		}
+		p.src = pwned.RangerSource(r)
+	}
+}
+
+// WithClient checks passwords against a pwned server with
+// c.Search, and variants with c.Range. opts are passed to
+// each call.
+func WithClient(c *pwnedgrpc.Client, opts ...grpc.CallOption) Option {
+	return func(p *Policy) {
+		p.search = func(ctx context.Context, password string) (int, error) {
+			return c.Search(ctx, password, opts...)
+		}
+		p.src = pwnedgrpc.ClientSource(c, opts...)
+	}
+}
+
+// WithBreachThreshold sets the number of times a password
+// must have been seen to be rejected. It defaults to
+// DefaultBreachThreshold. As the counts are rounded down
+// to a power of two, n should be a power of two.
+func WithBreachThreshold(n int) Option {
+	if n < 1 {
+		panic("pwned/policy: breach threshold must be positive")
+	}
+
+	return func(p *Policy) {
+		p.threshold = n
+	}
+}
+
+// WithVariants also checks the normalised variants of each
+// password, as returned by Variants, against the pwned
+// password list given to WithRanger or WithClient. The
+// most often seen variant is reported as a BreachedVariant
+// violation if it meets the breach threshold. The variant
+// itself is not reported as it is derived from the
+// password.
+//
+// The variants are looked up with range queries, grouped
+// so that each distinct prefix is only queried once, and
+// queried concurrently as set by WithConcurrency.
+func WithVariants() Option {
+	return func(p *Policy) {
+		p.variants = true
+	}
+}
+
+// WithConcurrency sets the number of prefixes that are
+// queried concurrently when checking variants. It defaults
+// to DefaultConcurrency.
+func WithConcurrency(n int) Option {
+	return func(p *Policy) {
+		p.concurrency = n
+	}
+}
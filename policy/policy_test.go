@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+func rules(violations []Violation) []Rule {
+	var rules []Rule
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+
+	return rules
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	words := []string{"correct horse"}
+	for i := 0; i < 20; i++ {
+		words = append(words, "password1")
+	}
+
+	p := New(WithRanger(wordlist.New(words...)), WithContextWords("example", "ex"))
+
+	for password, want := range map[string][]Rule{
+		"Tr0ub4dor&3":                          nil,
+		"correct horse battery staple":         nil,
+		"short":                                {TooShort},
+		"ünïcödé":                              {TooShort},
+		"ünïcödé!":                             nil,
+		strings.Repeat("x7Q!", 17):             {TooLong},
+		"zzzzTr0ub4dor":                        {Repetitive},
+		"zzzTr0ub4dor":                         nil,
+		"Tr0ub4dor&1234":                       {Sequential},
+		"Tr0ub4dor&DCBA":                       {Sequential},
+		"Tr0ub4dor&abcba":                      nil,
+		"my-EXAMPLE-password":                  {ContextWord},
+		"alice-Tr0ub4dor":                      {ContextWord},
+		"password1":                            {Breached},
+		"correct horse":                        {Breached},
+		"aaaa":                                 {TooShort, Repetitive},
+		"exTr0ub4dor":                          nil,
+		"Tr0ub4dor&3" + strings.Repeat("!", 3): nil,
+	} {
+		violations, err := p.Check(context.Background(), password, "Alice")
+		require.NoError(t, err)
+		assert.Equal(t, want, rules(violations), password)
+
+		for _, v := range violations {
+			assert.NotContains(t, v.Message, password)
+		}
+	}
+}
+
+func TestCheckThreshold(t *testing.T) {
+	t.Parallel()
+
+	words := []string{"correct horse"}
+	for i := 0; i < 20; i++ {
+		words = append(words, "password1")
+	}
+
+	p := New(WithRanger(wordlist.New(words...)), WithBreachThreshold(16))
+
+	violations, err := p.Check(context.Background(), "correct horse")
+	require.NoError(t, err)
+	assert.Nil(t, violations)
+
+	violations, err = p.Check(context.Background(), "password1")
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{
+		{Rule: Breached, Message: "password has appeared in a data breach", Count: 16},
+	}, violations)
+}
+
+func TestVariants(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"password1!", "passwordi!", "passwordl!", "password"},
+		Variants("Password1!"))
+	assert.Equal(t, []string{"p@ssw0rd", "password"}, Variants("P@ssw0rd"))
+	assert.Equal(t, []string{"summer2019!", "summer2oi9!", "summer2ol9!", "summer"},
+		Variants("Summer2019!"))
+	assert.Contains(t, Variants("Tr0ub4dor&1999"), "troubador&")
+	assert.Empty(t, Variants("password"))
+	assert.Empty(t, Variants("1234"))
+}
+
+type countingRanger struct {
+	pwned.Ranger
+
+	mu       sync.Mutex
+	prefixes []string
+	inFlight int
+	max      int
+}
+
+func (r *countingRanger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	r.mu.Lock()
+	r.prefixes = append(r.prefixes, prefix)
+	if r.inFlight++; r.inFlight > r.max {
+		r.max = r.inFlight
+	}
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	return r.Ranger.Range(ctx, prefix)
+}
+
+func TestCheckVariants(t *testing.T) {
+	t.Parallel()
+
+	words := []string{"Password1!"}
+	for i := 0; i < 20; i++ {
+		words = append(words, "password")
+	}
+
+	r := &countingRanger{Ranger: wordlist.New(words...)}
+	p := New(WithRanger(r), WithVariants())
+
+	violations, err := p.Check(context.Background(), "P@ssw0rd2019!")
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{
+		Rule:    BreachedVariant,
+		Message: "password is a variant of a password that has appeared in a data breach",
+		Count:   16,
+	}}, violations)
+
+	variants := Variants("P@ssw0rd2019!")
+	assert.True(t, len(r.prefixes) <= len(variants)+1)
+
+	seen := make(map[string]bool)
+	for _, prefix := range r.prefixes[1:] {
+		assert.False(t, seen[prefix], "prefix %s queried twice", prefix)
+		seen[prefix] = true
+	}
+
+	violations, err = New(WithRanger(r)).Check(context.Background(), "P@ssw0rd2019!")
+	require.NoError(t, err)
+	assert.Nil(t, violations)
+
+	violations, err = p.Check(context.Background(), "Password1!")
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{Breached, BreachedVariant}, rules(violations))
+}
+
+func TestCheckVariantsConcurrency(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{1, 2} {
+		r := &countingRanger{Ranger: wordlist.New("password")}
+		p := New(WithRanger(r), WithVariants(), WithConcurrency(n))
+
+		_, err := p.Check(context.Background(), "P@ssw0rd2019!")
+		require.NoError(t, err)
+		assert.Equal(t, n, r.max)
+	}
+}
+
+type failingRanger struct{}
+
+func (failingRanger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+var _ pwned.Ranger = failingRanger{}
+
+func TestCheckError(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(WithRanger(failingRanger{})).Check(context.Background(), "Tr0ub4dor&3")
+	assert.EqualError(t, err, "failed")
+
+	violations, err := New().Check(context.Background(), "abcd1234")
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{Sequential}, rules(violations))
+}
+
+func TestOptionsPanic(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { WithMaxLength(32) })
+	assert.NotPanics(t, func() { WithMaxLength(0) })
+	assert.Panics(t, func() { WithBreachThreshold(0) })
+}
@@ -0,0 +1,179 @@
+package policy
+
+import (
+	"context"
+	"crypto/sha1"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go.tmthrgd.dev/pwned"
+)
+
+// leet maps leet-speak substitutions back to letters. '1'
+// is ambiguous and handled by leetAlt.
+var leet = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"8", "b",
+	"@", "a",
+	"$", "s",
+	"+", "t",
+)
+
+// leetAlt is like leet, but maps '1' to 'l'.
+var leetAlt = strings.NewReplacer(
+	"0", "o",
+	"1", "l",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"8", "b",
+	"@", "a",
+	"$", "s",
+	"+", "t",
+)
+
+// Variants returns the normalised variants of password
+// that are checked when WithVariants is used: it is case
+// folded, then has any year suffix or trailing digits and
+// symbols stripped, and then has leet-speak substitutions
+// reversed, so that "P@ssw0rd2019!" yields "password".
+//
+// The variants are returned in a stable order without
+// duplicates and never include password itself.
+func Variants(password string) []string {
+	lower := strings.ToLower(password)
+
+	seen := map[string]bool{password: true, "": true}
+	var variants []string
+	for _, base := range []string{lower, stripYear(lower), stripTrailing(lower)} {
+		vs := []string{base}
+		if strings.IndexFunc(base, unicode.IsLetter) >= 0 {
+			// Only reverse leet-speak in words.
+			vs = append(vs, leet.Replace(base), leetAlt.Replace(base))
+		}
+
+		for _, v := range vs {
+			if !seen[v] {
+				seen[v] = true
+				variants = append(variants, v)
+			}
+		}
+	}
+
+	return variants
+}
+
+// stripTrailing removes any trailing digits and symbols.
+func stripTrailing(s string) string {
+	return strings.TrimRightFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+// stripYear removes a trailing year between 1900 and 2099,
+// and any symbols after it.
+func stripYear(s string) string {
+	t := strings.TrimRightFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	})
+	if len(t) < 4 {
+		return s
+	}
+
+	year := t[len(t)-4:]
+	if (!strings.HasPrefix(year, "19") && !strings.HasPrefix(year, "20")) ||
+		strings.IndexFunc(year, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return s
+	}
+
+	return t[:len(t)-4]
+}
+
+// checkVariants searches for each variant of password and
+// returns the count of the most often seen variant. The
+// variants are grouped by prefix so that each distinct
+// prefix is only queried once, and up to p.concurrency
+// prefixes are queried at a time.
+func (p *Policy) checkVariants(ctx context.Context, password string) (count int, err error) {
+	type candidate struct {
+		variant string
+		suffix  [pwned.SuffixSize]byte
+	}
+
+	groups := make(map[string][]candidate)
+	for _, v := range Variants(password) {
+		prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte(v)))
+		groups[prefix] = append(groups[prefix], candidate{v, suffix})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := p.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for prefix, candidates := range groups {
+		wg.Add(1)
+		go func(prefix string, candidates []candidate) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			res, bits, err := p.src.Range(ctx, prefix)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+
+				cancel()
+				return
+			}
+
+			for _, c := range candidates {
+				var n int
+				if bits != 0 {
+					n = pwned.SearchTruncatedSet(res, c.suffix, bits)
+				} else {
+					n = pwned.SearchSet(res, c.suffix)
+				}
+
+				if n > count {
+					count = n
+				}
+			}
+		}(prefix, candidates)
+	}
+
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		// The parent context was cancelled.
+		return 0, ctx.Err()
+	}
+
+	return count, firstErr
+}
@@ -7,8 +7,10 @@ import (
 	"crypto/sha1"
 	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"math/bits"
 	"strconv"
+	"time"
 )
 
 const (
@@ -53,21 +55,56 @@ func SearchSet(set []byte, suffix [SuffixSize]byte) (count int) {
 	//   average: N=478 -> 9.40µs ± 2%
 	//   maximum: N=584 -> 11.9µs ± 2%
 
-	if len(set)%(SuffixSize+1) != 0 {
+	return searchSet(set, suffix[:])
+}
+
+// TruncatedSuffixSize returns the length of a suffix, in
+// bytes, when the whole hash is truncated to the given
+// number of bits. hashBits must be a multiple of eight
+// between 32 and 160.
+func TruncatedSuffixSize(hashBits int) int {
+	if hashBits%8 != 0 || hashBits < 32 || hashBits > 8*sha1.Size {
+		panic("pwned: invalid truncated hash size")
+	}
+
+	return hashBits/8 - PrefixSize/2
+}
+
+// AppendTruncatedResult is like AppendResult except that
+// the hash is truncated to the given number of bits, as
+// described by TruncatedSuffixSize.
+func AppendTruncatedResult(buf []byte, suffix [SuffixSize]byte, hashBits int, count uint64) []byte {
+	buf = append(buf, suffix[:TruncatedSuffixSize(hashBits)]...)
+
+	n := 63 - bits.LeadingZeros64(count)
+	return append(buf, byte(n))
+}
+
+// SearchTruncatedSet is like SearchSet except that set
+// contains suffixes that were truncated to the given
+// number of bits by AppendTruncatedResult. Only the
+// corresponding leading bytes of suffix are compared.
+func SearchTruncatedSet(set []byte, suffix [SuffixSize]byte, hashBits int) (count int) {
+	return searchSet(set, suffix[:TruncatedSuffixSize(hashBits)])
+}
+
+func searchSet(set []byte, suffix []byte) (count int) {
+	size := len(suffix)
+	if len(set)%(size+1) != 0 {
 		panic("pwned: invariant invalid result set")
 	}
 
-	for i := 0; i < len(set); i += SuffixSize + 1 {
-		if subtle.ConstantTimeCompare(suffix[:], set[i:i+SuffixSize]) != 1 {
+	for i := 0; i < len(set); i += size + 1 {
+		if subtle.ConstantTimeCompare(suffix, set[i:i+size]) != 1 {
 			continue
 		}
 
-		if set[i+SuffixSize] > strconv.IntSize-1 {
+		if set[i+size] > strconv.IntSize-1 {
 			const maxInt = int(^uint(0) >> 1)
 			return maxInt
 		}
 
-		return 1 << set[i+SuffixSize]
+		return 1 << set[i+size]
 	}
 
 	return 0
@@ -81,3 +118,154 @@ func SearchSet(set []byte, suffix [SuffixSize]byte) (count int) {
 type Ranger interface {
 	Range(ctx context.Context, prefix string) ([]byte, error)
 }
+
+// RangeAppender contains an optional method that Ranger's
+// may implement to append their results to a buffer
+// provided by the caller rather than allocating a new one
+// for every call.
+//
+// RangeAppend must return the same results as Range would,
+// appended to dst. dst may be reused by the caller once
+// RangeAppend returns, so it must not be retained.
+type RangeAppender interface {
+	Ranger
+	RangeAppend(ctx context.Context, dst []byte, prefix string) ([]byte, error)
+}
+
+// RangeAppend appends the results of a range query to dst.
+// It uses the RangeAppend method of r if r implements
+// RangeAppender.
+func RangeAppend(ctx context.Context, r Ranger, dst []byte, prefix string) ([]byte, error) {
+	if ra, ok := r.(RangeAppender); ok {
+		return ra.RangeAppend(ctx, dst, prefix)
+	}
+
+	res, err := r.Range(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(dst, res...), nil
+}
+
+// Search returns the number of times the password occurs
+// in the results of r. It returns (0, nil) if the password
+// was not found. The returned count will always be a power
+// of two, less than or equal to the actual count.
+//
+// Only the prefix of the password's hash is passed to r.
+// If r implements pwnedgrpc.Truncated, the results are
+// searched with SearchTruncatedSet.
+func Search(ctx context.Context, r Ranger, password string) (count int, err error) {
+	return search(ctx, r, sha1.Sum([]byte(password)))
+}
+
+func search(ctx context.Context, r Ranger, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := SplitDigest(digest)
+
+	res, err := r.Range(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var bits int
+	if t, ok := r.(truncated); ok {
+		bits = t.TruncatedBits()
+	}
+
+	if bits != 0 {
+		if len(res)%(TruncatedSuffixSize(bits)+1) != 0 {
+			return 0, errors.New("pwned: invalid result set returned")
+		}
+
+		return SearchTruncatedSet(res, suffix, bits), nil
+	}
+
+	if len(res)%(SuffixSize+1) != 0 {
+		return 0, errors.New("pwned: invalid result set returned")
+	}
+
+	return SearchSet(res, suffix), nil
+}
+
+// Source is queried for the range results of a prefix,
+// such as when checking many passwords at once. If
+// truncatedBits is non-zero, the results contain truncated
+// hashes, as produced by AppendTruncatedResult.
+type Source interface {
+	Range(ctx context.Context, prefix string) (results []byte, truncatedBits int, err error)
+}
+
+type rangerSource struct{ r Ranger }
+
+// RangerSource returns a Source that queries r. If r
+// implements pwnedgrpc.Truncated, its results are treated
+// as truncated.
+func RangerSource(r Ranger) Source {
+	return rangerSource{r}
+}
+
+func (s rangerSource) Range(ctx context.Context, prefix string) ([]byte, int, error) {
+	var bits int
+	if t, ok := s.r.(truncated); ok {
+		bits = t.TruncatedBits()
+	}
+
+	res, err := s.r.Range(ctx, prefix)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := SuffixSize
+	if bits != 0 {
+		size = TruncatedSuffixSize(bits)
+	}
+
+	if len(res)%(size+1) != 0 {
+		return nil, 0, errors.New("pwned: invalid result set returned")
+	}
+
+	return res, bits, nil
+}
+
+// Info describes the dataset served by a Ranger.
+type Info struct {
+	// Version identifies the dataset, such as "v8" for
+	// version 8 of the Pwned Passwords list, or the date
+	// a mirror was crawled.
+	Version string
+
+	// Entries is the total number of hashes in the
+	// dataset, or zero if unknown.
+	Entries uint64
+
+	// Prefixes is the number of prefixes that have at
+	// least one hash, or zero if unknown.
+	Prefixes uint64
+
+	// Algorithm is the hash algorithm, such as "SHA1".
+	Algorithm string
+
+	// Encoding describes how the dataset is stored, such
+	// as "elias-fano" or "range-files".
+	Encoding string
+
+	// Built is when the dataset was built, or the zero
+	// time if unknown.
+	Built time.Time
+}
+
+// InfoRanger contains an optional method that Ranger's
+// may implement to describe their dataset.
+//
+// Ranger's that wrap another Ranger may implement
+// InfoRanger by forwarding to it. When the wrapped Ranger
+// does not implement InfoRanger, Info returns ErrNoInfo.
+type InfoRanger interface {
+	Ranger
+	Info(ctx context.Context) (*Info, error)
+}
+
+// ErrNoInfo is returned by InfoRanger.Info when the
+// dataset cannot be described.
+var ErrNoInfo = errors.New("pwned: dataset does not provide info")
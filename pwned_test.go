@@ -1,9 +1,14 @@
 package pwned
 
 import (
+	"context"
+	"crypto/sha1"
 	"fmt"
 	"math/rand"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func BenchmarkSearchSet(b *testing.B) {
@@ -33,3 +38,102 @@ func BenchmarkSearchSet(b *testing.B) {
 		})
 	}
 }
+
+type testRanger map[string][]byte
+
+func (r testRanger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return r[prefix], nil
+}
+
+type testLookup struct{ testRanger }
+
+func (testLookup) Lookup(ctx context.Context, digest [sha1.Size]byte) (int, error) {
+	return 1234, nil
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	digest := sha1.Sum([]byte("password"))
+	prefix, suffix := SplitDigest(digest)
+	r := testRanger{prefix: AppendResult(nil, suffix, 8)}
+
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Ranger) Ranger {
+			return rangeFunc(func(ctx context.Context, prefix string) ([]byte, error) {
+				calls = append(calls, name)
+				return next.Range(ctx, prefix)
+			})
+		}
+	}
+
+	chained := Chain(mw("a"), mw("b"))(r)
+	_, ok := chained.(lookuper)
+	assert.False(t, ok, "Chain must not add Lookup")
+
+	chained = Chain(mw("a"), mw("b"))(testLookup{r})
+	lookup, ok := chained.(lookuper)
+	require.True(t, ok, "Chain must restore Lookup")
+
+	count, err := lookup.Lookup(context.Background(), digest)
+	require.NoError(t, err)
+	assert.Equal(t, 1234, count, "Chain must forward to the inner Lookup")
+	assert.Empty(t, calls)
+
+	count, err = Search(context.Background(), chained, "password")
+	require.NoError(t, err)
+	assert.Equal(t, 8, count)
+	assert.Equal(t, []string{"a", "b"}, calls)
+
+	_, err = chained.(InfoRanger).Info(context.Background())
+	assert.Equal(t, ErrNoInfo, err)
+
+	chained = Chain()(testLookup{r})
+	count, err = chained.(lookuper).Lookup(context.Background(), digest)
+	require.NoError(t, err)
+	assert.Equal(t, 1234, count)
+}
+
+type rangeFunc func(ctx context.Context, prefix string) ([]byte, error)
+
+func (fn rangeFunc) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return fn(ctx, prefix)
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	digest := sha1.Sum([]byte("password"))
+	prefix, suffix := SplitDigest(digest)
+	r := testRanger{prefix: AppendResult(nil, suffix, 8)}
+
+	count, err := Search(context.Background(), r, "password")
+	require.NoError(t, err)
+	assert.Equal(t, 8, count)
+
+	count, err = Search(context.Background(), r, "P@ssw0rd")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = Search(context.Background(), testRanger{prefix: []byte{1, 2, 3}}, "password")
+	assert.Error(t, err)
+}
+
+func TestRiskOf(t *testing.T) {
+	t.Parallel()
+
+	for count, risk := range map[int]Risk{
+		0:       RiskNone,
+		1:       RiskLow,
+		8:       RiskLow,
+		16:      RiskMedium,
+		64:      RiskMedium,
+		128:     RiskHigh,
+		8192:    RiskHigh,
+		16384:   RiskCritical,
+		1 << 30: RiskCritical,
+	} {
+		assert.Equal(t, risk, RiskOf(count), "%d", count)
+	}
+}
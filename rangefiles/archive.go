@@ -0,0 +1,176 @@
+package rangefiles
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"go.tmthrgd.dev/pwned/internal/prefix"
+)
+
+// archivePrefix returns the upper case prefix of the
+// range file with the given name, or false if name does
+// not look like a range file.
+func archivePrefix(name string) (string, bool) {
+	name = path.Base(name)
+	if !strings.HasSuffix(strings.ToLower(name), ".txt") {
+		return "", false
+	}
+
+	pfx := name[:len(name)-len(".txt")]
+	if _, ok := prefix.Index(pfx); !ok {
+		return "", false
+	}
+
+	return strings.ToUpper(pfx), true
+}
+
+// OpenZip returns a Ranger that reads range files from the
+// zip archive at name.
+//
+// The range files are located through the archive's
+// central directory, so nothing is extracted to disk. They
+// may be in any directory within the archive, but must be
+// named after their prefix, as in 5BAA6.txt. Range files
+// may be stored or deflated.
+func OpenZip(name string, opts ...Option) (*Ranger, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/rangefiles: failed to open zip archive: %v", err)
+	}
+
+	src := &zipSource{
+		c:     zr,
+		files: make(map[string]*zip.File, len(zr.File)),
+	}
+
+	for _, f := range zr.File {
+		if pfx, ok := archivePrefix(f.Name); ok && f.Mode().IsRegular() {
+			src.files[pfx] = f
+		}
+	}
+
+	return newRanger(src, opts), nil
+}
+
+type zipSource struct {
+	c     io.Closer
+	files map[string]*zip.File
+}
+
+func (z *zipSource) open(prefix string) (io.ReadCloser, version, error) {
+	f, ok := z.files[prefix]
+	if !ok {
+		return nil, version{}, fmt.Errorf("%s.txt not found in zip archive", prefix)
+	}
+
+	rc, err := f.Open()
+	return rc, version{}, err
+}
+
+func (*zipSource) stat(prefix string) (version, error) {
+	return version{}, nil
+}
+
+func (z *zipSource) count() int {
+	return len(z.files)
+}
+
+func (z *zipSource) Close() error {
+	return z.c.Close()
+}
+
+// OpenTar returns a Ranger that reads range files from the
+// uncompressed tar archive at name.
+//
+// The archive is scanned once to build an index of where
+// each range file is, after which range files are read
+// directly from the archive without extracting anything
+// to disk. The range files may be in any directory within
+// the archive, but must be named after their prefix, as in
+// 5BAA6.txt.
+func OpenTar(name string, opts ...Option) (*Ranger, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/rangefiles: failed to open tar archive: %v", err)
+	}
+
+	src, err := newTarSource(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("pwned/rangefiles: failed to index tar archive: %v", err)
+	}
+
+	return newRanger(src, opts), nil
+}
+
+type tarSource struct {
+	f     *os.File
+	files map[string]*io.SectionReader
+}
+
+func newTarSource(f *os.File) (*tarSource, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// io.SectionReader implements io.Seeker, which allows
+	// tar.Reader to skip over the contents of each file
+	// and allows us to find the offset of each file.
+	sr := io.NewSectionReader(f, 0, fi.Size())
+	tr := tar.NewReader(sr)
+
+	src := &tarSource{
+		f:     f,
+		files: make(map[string]*io.SectionReader),
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return src, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pfx, ok := archivePrefix(hdr.Name)
+		if !ok || hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		off, err := sr.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+
+		src.files[pfx] = io.NewSectionReader(f, off, hdr.Size)
+	}
+}
+
+func (t *tarSource) open(prefix string) (io.ReadCloser, version, error) {
+	sr, ok := t.files[prefix]
+	if !ok {
+		return nil, version{}, fmt.Errorf("%s.txt not found in tar archive", prefix)
+	}
+
+	return ioutil.NopCloser(io.NewSectionReader(sr, 0, sr.Size())), version{}, nil
+}
+
+func (*tarSource) stat(prefix string) (version, error) {
+	return version{}, nil
+}
+
+func (t *tarSource) count() int {
+	return len(t.files)
+}
+
+func (t *tarSource) Close() error {
+	return t.f.Close()
+}
@@ -0,0 +1,57 @@
+package rangefiles
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewDir returns a Ranger that reads range files from dir.
+//
+// Range files are parsed on demand and are expected to be
+// named after their upper case prefix, as in 5BAA6.txt.
+// Lower case names are also accepted.
+func NewDir(dir string, opts ...Option) *Ranger {
+	return newRanger(dirSource(dir), opts)
+}
+
+type dirSource string
+
+func (d dirSource) path(prefix string) string {
+	return filepath.Join(string(d), prefix+".txt")
+}
+
+func (d dirSource) open(prefix string) (io.ReadCloser, version, error) {
+	f, err := os.Open(d.path(prefix))
+	if os.IsNotExist(err) {
+		f, err = os.Open(d.path(strings.ToLower(prefix)))
+	}
+	if err != nil {
+		return nil, version{}, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, version{}, err
+	}
+
+	return f, fileVersion(fi.ModTime(), fi.Size()), nil
+}
+
+func (d dirSource) stat(prefix string) (version, error) {
+	fi, err := os.Stat(d.path(prefix))
+	if os.IsNotExist(err) {
+		fi, err = os.Stat(d.path(strings.ToLower(prefix)))
+	}
+	if err != nil {
+		return version{}, err
+	}
+
+	return fileVersion(fi.ModTime(), fi.Size()), nil
+}
+
+func (dirSource) count() int { return 0 }
+
+func (dirSource) Close() error { return nil }
@@ -0,0 +1,262 @@
+// Package rangefiles provides a pwned.Ranger that serves
+// results from per-prefix range files, such as those
+// written by the official PwnedPasswordsDownloader.
+//
+// Each range file is named after its prefix, as in
+// 5BAA6.txt, and contains the body of a ‘Have I been
+// pwned?’ range query for that prefix. The range files can
+// be read from a directory, with NewDir, or directly from
+// a zip or tar archive, with OpenZip or OpenTar.
+package rangefiles
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/internal/prefix"
+	"go.tmthrgd.dev/pwned/passwords"
+)
+
+// DefaultCacheSize is the default number of parsed range
+// files that are cached.
+const DefaultCacheSize = 4096
+
+// source provides access to the range files.
+type source interface {
+	// open returns the range file for prefix along with
+	// a version that changes whenever the file does.
+	open(prefix string) (io.ReadCloser, version, error)
+	// stat returns the current version of the range
+	// file for prefix.
+	stat(prefix string) (version, error)
+	// count returns the number of range files, or zero
+	// if unknown.
+	count() int
+
+	io.Closer
+}
+
+type version struct {
+	modTime int64
+	size    int64
+}
+
+func fileVersion(modTime time.Time, size int64) version {
+	return version{modTime.UnixNano(), size}
+}
+
+// Ranger is a pwned.Ranger that serves results from range
+// files. It also implements pwned.RangeAppender and
+// pwnedgrpc.Cache.
+//
+// A Ranger is safe for concurrent use.
+type Ranger struct {
+	src source
+
+	watch     bool
+	cacheSize int
+	version   string
+
+	mu    sync.Mutex
+	lru   *list.List
+	cache map[string]*list.Element
+
+	hits, misses uint64
+}
+
+type cacheEntry struct {
+	prefix  string
+	set     []byte
+	version version
+}
+
+func newRanger(src source, opts []Option) *Ranger {
+	r := &Ranger{
+		src: src,
+
+		cacheSize: DefaultCacheSize,
+
+		lru:   list.New(),
+		cache: make(map[string]*list.Element),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Range implements pwned.Ranger.
+func (r *Ranger) Range(ctx context.Context, pfx string) ([]byte, error) {
+	return r.RangeAppend(ctx, nil, pfx)
+}
+
+// RangeAppend implements pwned.RangeAppender.
+func (r *Ranger) RangeAppend(ctx context.Context, dst []byte, pfx string) ([]byte, error) {
+	if _, ok := prefix.Index(pfx); !ok {
+		return nil, fmt.Errorf("pwned/rangefiles: invalid prefix %q", pfx)
+	}
+
+	pfx = strings.ToUpper(pfx)
+
+	if set, ok := r.cached(pfx); ok {
+		return append(dst, set...), nil
+	}
+
+	f, v, err := r.src.open(pfx)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/rangefiles: failed to open range file: %v", err)
+	}
+	defer f.Close()
+
+	const smallest = 381
+	set := make([]byte, 0, pwned.Size(smallest))
+
+	rr := passwords.NewResultsReader(f, pfx)
+
+	for rr.Scan() {
+		_, suffix, count := rr.Entry()
+		if count == 0 {
+			// Skip padding entries.
+			continue
+		}
+
+		set = pwned.AppendResult(set, suffix, count)
+	}
+
+	if rr.Err() != nil {
+		return nil, fmt.Errorf("pwned/rangefiles: reader returned error: %v", rr.Err())
+	}
+
+	r.add(pfx, set, v)
+	return append(dst, set...), nil
+}
+
+func (r *Ranger) cached(prefix string) ([]byte, bool) {
+	r.mu.Lock()
+	e, ok := r.cache[prefix]
+	if ok {
+		r.lru.MoveToFront(e)
+	} else {
+		r.misses++
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	ce := e.Value.(*cacheEntry)
+	if r.watch {
+		if v, err := r.src.stat(prefix); err != nil || v != ce.version {
+			r.mu.Lock()
+			r.misses++
+			r.mu.Unlock()
+			return nil, false
+		}
+	}
+
+	r.mu.Lock()
+	r.hits++
+	r.mu.Unlock()
+	return ce.set, true
+}
+
+func (r *Ranger) add(prefix string, set []byte, v version) {
+	if r.cacheSize <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.cache[prefix]; ok {
+		e.Value = &cacheEntry{prefix, set, v}
+		r.lru.MoveToFront(e)
+		return
+	}
+
+	r.cache[prefix] = r.lru.PushFront(&cacheEntry{prefix, set, v})
+
+	for r.lru.Len() > r.cacheSize {
+		e := r.lru.Back()
+		r.lru.Remove(e)
+		delete(r.cache, e.Value.(*cacheEntry).prefix)
+	}
+}
+
+// Purge implements pwnedgrpc.Cache. It removes every
+// parsed range file from the cache.
+func (r *Ranger) Purge() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.lru.Len()
+	r.lru.Init()
+	r.cache = make(map[string]*list.Element)
+	return n
+}
+
+// CacheStats implements pwnedgrpc.Cache.
+func (r *Ranger) CacheStats() (hits, misses uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hits, r.misses
+}
+
+// Info implements pwned.InfoRanger. The number of entries
+// is never known and the number of prefixes is only known
+// for archives.
+func (r *Ranger) Info(ctx context.Context) (*pwned.Info, error) {
+	return &pwned.Info{
+		Version:   r.version,
+		Prefixes:  uint64(r.src.count()),
+		Algorithm: "SHA1",
+		Encoding:  "range-files",
+	}, nil
+}
+
+// Close releases any resources held by the Ranger.
+func (r *Ranger) Close() error {
+	return r.src.Close()
+}
+
+// Option allows the behaviour of the Ranger to be
+// configured.
+type Option func(*Ranger)
+
+// WithCacheSize sets the maximum number of parsed range
+// files that are kept in memory. A size of zero disables
+// the cache. It defaults to DefaultCacheSize.
+func WithCacheSize(size int) Option {
+	return func(r *Ranger) {
+		r.cacheSize = size
+	}
+}
+
+// WithWatch causes the Ranger to check whether a range
+// file has changed, by comparing its size and
+// modification time, each time a cached result is used.
+// Changed files are parsed again. It is only supported by
+// NewDir.
+func WithWatch() Option {
+	return func(r *Ranger) {
+		r.watch = true
+	}
+}
+
+// WithVersion records the version of the dataset, such as
+// the date the range files were downloaded, as reported
+// by Info.
+func WithVersion(version string) Option {
+	return func(r *Ranger) {
+		r.version = version
+	}
+}
@@ -0,0 +1,192 @@
+package rangefiles
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+)
+
+func rangeFile(padding bool, entries map[string]int) (prefix, body string) {
+	var lines []string
+	for password, count := range entries {
+		digest := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(digest[:]))
+
+		prefix = hash[:pwned.PrefixSize]
+		lines = append(lines, hash[pwned.PrefixSize:]+":"+strconv.Itoa(count))
+	}
+
+	if padding {
+		lines = append(lines, "0000000000000000000000000000000000A:0")
+	}
+
+	return prefix, strings.Join(lines, "\r\n")
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "pwned-rangefiles")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	prefix, body := rangeFile(true, map[string]int{"password": 8})
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(body), 0644))
+
+	r := NewDir(dir)
+	defer r.Close()
+
+	digest := sha1.Sum([]byte("password"))
+	_, suffix := pwned.SplitDigest(digest)
+
+	for _, pfx := range []string{prefix, strings.ToLower(prefix), prefix} {
+		set, err := r.Range(context.Background(), pfx)
+		require.NoError(t, err)
+		assert.Equal(t, pwned.AppendResult(nil, suffix, 8), set)
+	}
+
+	hits, misses := r.CacheStats()
+	assert.Equal(t, uint64(2), hits)
+	assert.Equal(t, uint64(1), misses)
+	assert.Equal(t, 1, r.Purge())
+	assert.Equal(t, 0, r.Purge())
+
+	_, err := r.Range(context.Background(), "00000")
+	assert.Error(t, err)
+
+	_, err = r.Range(context.Background(), "../..")
+	assert.Error(t, err)
+}
+
+func TestDirWatch(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	prefix, body := rangeFile(false, map[string]int{"password": 8})
+	path := filepath.Join(dir, prefix+".txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+
+	r := NewDir(dir, WithWatch())
+	defer r.Close()
+
+	digest := sha1.Sum([]byte("password"))
+	_, suffix := pwned.SplitDigest(digest)
+
+	set, err := r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Equal(t, 8, pwned.SearchSet(set, suffix))
+
+	_, body = rangeFile(false, map[string]int{"password": 16})
+	require.NoError(t, ioutil.WriteFile(path, []byte(body), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	set, err = r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Equal(t, 16, pwned.SearchSet(set, suffix))
+}
+
+func TestZip(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	f, err := os.Create(filepath.Join(dir, "ranges.zip"))
+	require.NoError(t, err)
+
+	zw := zip.NewWriter(f)
+
+	prefix, body := rangeFile(true, map[string]int{"password": 8})
+	w, err := zw.Create("ranges/" + prefix + ".txt")
+	require.NoError(t, err)
+	_, err = io.WriteString(w, body)
+	require.NoError(t, err)
+
+	_, err = zw.Create("README")
+	require.NoError(t, err)
+
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	r, err := OpenZip(f.Name())
+	require.NoError(t, err)
+	defer r.Close()
+
+	testArchive(t, r, prefix)
+}
+
+func TestTar(t *testing.T) {
+	t.Parallel()
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	f, err := os.Create(filepath.Join(dir, "ranges.tar"))
+	require.NoError(t, err)
+
+	tw := tar.NewWriter(f)
+
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "README",
+		Typeflag: tar.TypeReg,
+		Size:     3,
+		Mode:     0644,
+	}))
+	_, err = io.WriteString(tw, "abc")
+	require.NoError(t, err)
+
+	prefix, body := rangeFile(true, map[string]int{"password": 8})
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     "ranges/" + prefix + ".txt",
+		Typeflag: tar.TypeReg,
+		Size:     int64(len(body)),
+		Mode:     0644,
+	}))
+	_, err = io.WriteString(tw, body)
+	require.NoError(t, err)
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, f.Close())
+
+	r, err := OpenTar(f.Name())
+	require.NoError(t, err)
+	defer r.Close()
+
+	testArchive(t, r, prefix)
+}
+
+func testArchive(t *testing.T, r *Ranger, prefix string) {
+	digest := sha1.Sum([]byte("password"))
+	_, suffix := pwned.SplitDigest(digest)
+
+	for _, pfx := range []string{prefix, strings.ToLower(prefix), prefix} {
+		set, err := r.Range(context.Background(), pfx)
+		require.NoError(t, err)
+		assert.Equal(t, pwned.AppendResult(nil, suffix, 8), set)
+	}
+
+	_, err := r.Range(context.Background(), "00000")
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package pwned
+
+import "strconv"
+
+// Risk is a coarse band for how often a password has been
+// seen in breaches.
+type Risk int
+
+// The risk bands. As counts are rounded down to a power of
+// two, the bands start at 1, 16, 128 and 16384.
+const (
+	RiskNone     Risk = iota // not seen
+	RiskLow                  // seen fewer than 10 times
+	RiskMedium               // seen fewer than 100 times
+	RiskHigh                 // seen fewer than 10,000 times
+	RiskCritical             // seen 10,000 times or more
+)
+
+var riskNames = [...]string{
+	RiskNone:     "none",
+	RiskLow:      "low",
+	RiskMedium:   "medium",
+	RiskHigh:     "high",
+	RiskCritical: "critical",
+}
+
+// RiskOf returns the risk band for a password that has
+// been seen count times.
+func RiskOf(count int) Risk {
+	switch {
+	case count <= 0:
+		return RiskNone
+	case count < 10:
+		return RiskLow
+	case count < 100:
+		return RiskMedium
+	case count < 10000:
+		return RiskHigh
+	default:
+		return RiskCritical
+	}
+}
+
+func (r Risk) String() string {
+	if r < 0 || int(r) >= len(riskNames) {
+		return "Risk(" + strconv.Itoa(int(r)) + ")"
+	}
+
+	return riskNames[r]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Risk) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
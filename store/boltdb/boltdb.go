@@ -0,0 +1,400 @@
+// Package boltdb provides a mutable password store backed
+// by an embedded bbolt database.
+//
+// Unlike the other stores, entries can be added, removed
+// and updated at runtime without rebuilding the store.
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/internal/prefix"
+	"go.tmthrgd.dev/pwned/passwords"
+)
+
+// rangesBucket holds one key per prefix. Each value is a
+// sorted list of records, where each record is:
+//  suffix, 18-bytes;
+//  count, 8-bytes, big endian.
+var rangesBucket = []byte("ranges")
+
+// metaBucket holds information about the dataset. The
+// entries and prefixes keys are kept up to date as the
+// ranges bucket is modified.
+var (
+	metaBucket = []byte("meta")
+
+	versionKey  = []byte("version")
+	builtKey    = []byte("built")
+	entriesKey  = []byte("entries")
+	prefixesKey = []byte("prefixes")
+)
+
+const recordSize = pwned.SuffixSize + 8
+
+// openTimeout is how long Open waits for the exclusive lock
+// on a database that is already open, whether in this
+// process or another.
+const openTimeout = time.Second
+
+// Store is a mutable password store. It implements both
+// pwned.Ranger and pwnedgrpc.Lookup.
+//
+// A Store is safe for concurrent use.
+type Store struct {
+	db *bbolt.DB
+}
+
+// Open opens the Store at path, creating it if it does not
+// already exist. A database can only be open once at a
+// time, so Open fails if the database is already open.
+func Open(path string, mode os.FileMode) (*Store, error) {
+	db, err := bbolt.Open(path, mode, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("pwned/boltdb: failed to open database: %v", err)
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(rangesBucket); err != nil {
+			return err
+		}
+
+		_, err := tx.CreateBucketIfNotExists(metaBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("pwned/boltdb: failed to create bucket: %v", err)
+	}
+
+	return &Store{db}, nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func key(pfx string) ([]byte, error) {
+	if _, ok := prefix.Index(pfx); !ok {
+		return nil, errors.New("pwned/boltdb: invalid prefix")
+	}
+
+	return []byte(strings.ToLower(pfx)), nil
+}
+
+// Range implements pwned.Ranger.
+func (s *Store) Range(ctx context.Context, prefix string) ([]byte, error) {
+	k, err := key(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var set []byte
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		val := tx.Bucket(rangesBucket).Get(k)
+
+		set = make([]byte, 0, pwned.Size(len(val)/recordSize))
+		for ; len(val) >= recordSize; val = val[recordSize:] {
+			var suffix [pwned.SuffixSize]byte
+			copy(suffix[:], val)
+
+			set = pwned.AppendResult(set, suffix, binary.BigEndian.Uint64(val[pwned.SuffixSize:]))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pwned/boltdb: database error: %v", err)
+	}
+
+	return set, nil
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (s *Store) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		val := tx.Bucket(rangesBucket).Get([]byte(prefix))
+
+		i, ok := search(val, suffix)
+		if !ok {
+			return nil
+		}
+
+		set := pwned.AppendResult(nil, suffix, binary.BigEndian.Uint64(val[i+pwned.SuffixSize:]))
+		count = pwned.SearchSet(set, suffix)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("pwned/boltdb: database error: %v", err)
+	}
+
+	return count, nil
+}
+
+// Add increases the count of digest by count, adding it
+// to the Store if it is not already present.
+func (s *Store) Add(digest [sha1.Size]byte, count uint64) error {
+	return s.update(digest, func(old uint64) uint64 {
+		if old+count < old {
+			return math.MaxUint64
+		}
+
+		return old + count
+	})
+}
+
+// SetCount sets the count of digest, adding it to the
+// Store if it is not already present. A count of zero
+// removes digest from the Store.
+func (s *Store) SetCount(digest [sha1.Size]byte, count uint64) error {
+	return s.update(digest, func(uint64) uint64 {
+		return count
+	})
+}
+
+// Remove removes digest from the Store. It is not an error
+// if digest is not present.
+func (s *Store) Remove(digest [sha1.Size]byte) error {
+	return s.SetCount(digest, 0)
+}
+
+func (s *Store) update(digest [sha1.Size]byte, fn func(old uint64) uint64) error {
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	if err := s.db.Update(func(tx *bbolt.Tx) error {
+		w := newWriter(tx)
+		val := append([]byte(nil), w.ranges.Get([]byte(prefix))...)
+		if err := w.put([]byte(prefix), apply(val, suffix, fn)); err != nil {
+			return err
+		}
+
+		return w.commit()
+	}); err != nil {
+		return fmt.Errorf("pwned/boltdb: database error: %v", err)
+	}
+
+	return nil
+}
+
+// Import sets the count of every entry returned by r, as
+// with SetCount, in a single transaction. If an error
+// occurs, none of the entries are imported.
+//
+// r may be either a dataset or a results reader. Import
+// is most efficient when the entries are ordered by
+// prefix.
+func (s *Store) Import(r *passwords.Reader) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		w := newWriter(tx)
+
+		var (
+			k   []byte
+			val []byte
+		)
+		for r.Scan() {
+			pfx, suffix, count := r.Entry()
+
+			next, err := key(pfx)
+			if err != nil {
+				return err
+			}
+
+			if !bytes.Equal(k, next) {
+				if k != nil {
+					if err := w.put(k, val); err != nil {
+						return err
+					}
+				}
+
+				k, val = next, append([]byte(nil), w.ranges.Get(next)...)
+			}
+
+			val = apply(val, suffix, func(uint64) uint64 {
+				return count
+			})
+		}
+
+		if r.Err() != nil {
+			return fmt.Errorf("reader returned error: %v", r.Err())
+		}
+
+		if k != nil {
+			if err := w.put(k, val); err != nil {
+				return err
+			}
+		}
+
+		if err := w.meta.Put(builtKey, []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+			return err
+		}
+
+		return w.commit()
+	})
+	if err != nil {
+		return fmt.Errorf("pwned/boltdb: failed to import: %v", err)
+	}
+
+	return nil
+}
+
+// writer updates the ranges bucket and keeps track of the
+// changes to the number of entries and prefixes.
+type writer struct {
+	ranges, meta *bbolt.Bucket
+
+	entries, prefixes int64
+}
+
+func newWriter(tx *bbolt.Tx) *writer {
+	return &writer{
+		ranges: tx.Bucket(rangesBucket),
+		meta:   tx.Bucket(metaBucket),
+	}
+}
+
+func (w *writer) put(k, val []byte) error {
+	old, n := len(w.ranges.Get(k))/recordSize, len(val)/recordSize
+	w.entries += int64(n - old)
+
+	switch {
+	case old == 0 && n != 0:
+		w.prefixes++
+	case old != 0 && n == 0:
+		w.prefixes--
+	}
+
+	if n == 0 {
+		return w.ranges.Delete(k)
+	}
+
+	return w.ranges.Put(k, val)
+}
+
+// commit records the changes to the number of entries and
+// prefixes in the meta bucket.
+func (w *writer) commit() error {
+	for _, c := range []struct {
+		key   []byte
+		delta int64
+	}{
+		{entriesKey, w.entries},
+		{prefixesKey, w.prefixes},
+	} {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(int64(getUint64(w.meta, c.key))+c.delta))
+
+		if err := w.meta.Put(c.key, buf[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getUint64(b *bbolt.Bucket, k []byte) uint64 {
+	if v := b.Get(k); len(v) == 8 {
+		return binary.BigEndian.Uint64(v)
+	}
+
+	return 0
+}
+
+// SetVersion records the version of the dataset, as
+// reported by Info.
+func (s *Store) SetVersion(version string) error {
+	if err := s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(versionKey, []byte(version))
+	}); err != nil {
+		return fmt.Errorf("pwned/boltdb: database error: %v", err)
+	}
+
+	return nil
+}
+
+// Info implements pwned.InfoRanger. Built is the time of
+// the most recent call to Import.
+func (s *Store) Info(ctx context.Context) (*pwned.Info, error) {
+	info := &pwned.Info{
+		Algorithm: "SHA1",
+		Encoding:  "bbolt",
+	}
+
+	if err := s.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(metaBucket)
+
+		info.Version = string(b.Get(versionKey))
+		info.Entries = getUint64(b, entriesKey)
+		info.Prefixes = getUint64(b, prefixesKey)
+
+		if v := b.Get(builtKey); v != nil {
+			built, err := time.Parse(time.RFC3339, string(v))
+			if err != nil {
+				return err
+			}
+
+			info.Built = built
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("pwned/boltdb: database error: %v", err)
+	}
+
+	return info, nil
+}
+
+// search returns the offset of the record for suffix in
+// val, or where it would be inserted.
+func search(val []byte, suffix [pwned.SuffixSize]byte) (off int, found bool) {
+	n := len(val) / recordSize
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(val[i*recordSize:i*recordSize+pwned.SuffixSize], suffix[:]) >= 0
+	})
+
+	off = i * recordSize
+	return off, i < n && bytes.Equal(val[off:off+pwned.SuffixSize], suffix[:])
+}
+
+// apply updates the record for suffix in val with fn, which
+// is passed zero if the record does not exist, and returns
+// the updated slice. A new count of zero removes the
+// record. val is modified in place, so it must not be a
+// slice returned by bbolt.
+func apply(val []byte, suffix [pwned.SuffixSize]byte, fn func(old uint64) uint64) []byte {
+	off, found := search(val, suffix)
+
+	var old uint64
+	if found {
+		old = binary.BigEndian.Uint64(val[off+pwned.SuffixSize:])
+	}
+
+	switch count := fn(old); {
+	case found && count == 0:
+		return append(val[:off], val[off+recordSize:]...)
+	case found:
+		binary.BigEndian.PutUint64(val[off+pwned.SuffixSize:], count)
+		return val
+	case count == 0:
+		return val
+	default:
+		val = append(val, make([]byte, recordSize)...)
+		copy(val[off+recordSize:], val[off:])
+		copy(val[off:], suffix[:])
+		binary.BigEndian.PutUint64(val[off+pwned.SuffixSize:], count)
+		return val
+	}
+}
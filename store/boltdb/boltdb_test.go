@@ -0,0 +1,128 @@
+package boltdb
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/passwords"
+)
+
+func openStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "pwned-boltdb")
+	require.NoError(t, err)
+
+	s, err := Open(filepath.Join(dir, "pwned.db"), 0600)
+	require.NoError(t, err)
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func lookup(t *testing.T, s *Store, password string) int {
+	digest := sha1.Sum([]byte(password))
+
+	count, err := s.Lookup(context.Background(), digest)
+	require.NoError(t, err)
+
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	set, err := s.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Equal(t, count, pwned.SearchSet(set, suffix))
+
+	return count
+}
+
+func TestMutations(t *testing.T) {
+	t.Parallel()
+
+	s, cleanup := openStore(t)
+	defer cleanup()
+
+	digest := sha1.Sum([]byte("password"))
+
+	assert.Equal(t, 0, lookup(t, s, "password"))
+
+	require.NoError(t, s.Add(digest, 3))
+	assert.Equal(t, 2, lookup(t, s, "password"))
+
+	require.NoError(t, s.Add(digest, 5))
+	assert.Equal(t, 8, lookup(t, s, "password"))
+
+	require.NoError(t, s.SetCount(digest, 1))
+	assert.Equal(t, 1, lookup(t, s, "password"))
+
+	require.NoError(t, s.Remove(digest))
+	assert.Equal(t, 0, lookup(t, s, "password"))
+
+	require.NoError(t, s.Remove(digest))
+	assert.Equal(t, 0, lookup(t, s, "password"))
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	s, cleanup := openStore(t)
+	defer cleanup()
+
+	require.NoError(t, s.Add(sha1.Sum([]byte("P@ssw0rd")), 100))
+
+	var lines []string
+	for password, count := range map[string]string{
+		"password": "8",
+		"P@ssw0rd": "2",
+		"lauragpe": "0",
+		"melobie":  "4",
+	} {
+		digest := sha1.Sum([]byte(password))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(digest[:]))+":"+count)
+	}
+
+	require.NoError(t, s.Import(passwords.NewDatasetReader(strings.NewReader(strings.Join(lines, "\n")))))
+
+	assert.Equal(t, 8, lookup(t, s, "password"))
+	assert.Equal(t, 2, lookup(t, s, "P@ssw0rd"))
+	assert.Equal(t, 0, lookup(t, s, "lauragpe"))
+	assert.Equal(t, 4, lookup(t, s, "melobie"))
+
+	err := s.Import(passwords.NewDatasetReader(strings.NewReader(lines[0] + "\nnot a hash")))
+	assert.Error(t, err)
+
+	require.NoError(t, s.SetVersion("v8"))
+
+	info, err := s.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v8", info.Version)
+	assert.Equal(t, uint64(3), info.Entries)
+	assert.Equal(t, uint64(2), info.Prefixes)
+	assert.WithinDuration(t, time.Now(), info.Built, time.Minute)
+
+	require.NoError(t, s.Remove(sha1.Sum([]byte("password"))))
+
+	info, err = s.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.Entries)
+	assert.Equal(t, uint64(1), info.Prefixes)
+}
+
+func TestOpenLocked(t *testing.T) {
+	t.Parallel()
+
+	s, cleanup := openStore(t)
+	defer cleanup()
+
+	_, err := Open(s.db.Path(), 0600)
+	assert.Error(t, err)
+}
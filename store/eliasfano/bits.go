@@ -0,0 +1,81 @@
+package eliasfano
+
+// The bit strings used here are big-endian: bit 0 is the
+// most significant bit of the first byte. All writes are
+// OR'd into place, so the destination must be zeroed.
+
+// readBits returns the k <= 8 bits of b found at bit
+// offset off.
+func readBits(b []byte, off, k uint) uint8 {
+	i, s := off/8, off%8
+
+	w := uint16(b[i]) << 8
+	if i+1 < uint(len(b)) {
+		w |= uint16(b[i+1])
+	}
+
+	return uint8((w << s) >> (16 - k))
+}
+
+// writeBits stores the low k <= 8 bits of v into b at bit
+// offset off.
+func writeBits(b []byte, off uint, v uint8, k uint) {
+	i, s := off/8, off%8
+
+	w := uint16(v) << (16 - k) >> s
+	b[i] |= byte(w >> 8)
+	if i+1 < uint(len(b)) {
+		b[i+1] |= byte(w)
+	}
+}
+
+// getBits returns the n <= 64 bits of b found at bit
+// offset off.
+func getBits(b []byte, off, n uint) (v uint64) {
+	for n > 0 {
+		k := n
+		if k > 8 {
+			k = 8
+		}
+
+		v = v<<k | uint64(readBits(b, off, k))
+		off, n = off+k, n-k
+	}
+
+	return v
+}
+
+// putBits stores the low n <= 64 bits of v into b at bit
+// offset off.
+func putBits(b []byte, off uint, v uint64, n uint) {
+	for n > 0 {
+		k := n
+		if k > 8 {
+			k = 8
+		}
+
+		n -= k
+		writeBits(b, off, uint8(v>>n), k)
+		off += k
+	}
+}
+
+// copyBits copies n bits from src at bit offset soff into
+// dst at bit offset doff.
+func copyBits(dst []byte, doff uint, src []byte, soff uint, n uint) {
+	for n > 0 {
+		k := n
+		if k > 8 {
+			k = 8
+		}
+
+		writeBits(dst, doff, readBits(src, soff, k), k)
+		doff, soff, n = doff+k, soff+k, n-k
+	}
+}
+
+// bitBytes returns the number of bytes needed to hold n
+// bits.
+func bitBytes(n uint) int {
+	return int((n + 7) / 8)
+}
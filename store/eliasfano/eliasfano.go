@@ -0,0 +1,355 @@
+// Package eliasfano provides an in-memory password store
+// that keeps each prefix bucket Elias-Fano coded.
+//
+// Within a bucket the suffixes are sorted and uniformly
+// distributed, so the high bits of each suffix can be
+// stored in unary in roughly two bits per entry while the
+// remaining low bits are packed without any padding. The
+// buckets are decoded into the format produced by
+// pwned.AppendResult on demand.
+//
+// As SHA1 suffixes are effectively random, almost all of
+// their 140 bits carry information. Elias-Fano coding only
+// saves around log2(N) bits per entry, so the full dataset
+// takes roughly 17.7 bytes per entry rather than 19. Far
+// larger savings are only possible by discarding bits of
+// each hash.
+package eliasfano
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/internal/prefix"
+	"go.tmthrgd.dev/pwned/passwords"
+)
+
+// valueBits is the number of suffix bits that are coded.
+// The first nibble of each suffix is shared with the
+// prefix and so is implied by the bucket.
+const valueBits = 8*pwned.SuffixSize - 4
+
+// Store is an Elias-Fano coded password store. It
+// implements both pwned.Ranger and pwnedgrpc.Lookup.
+//
+// A Store is safe for concurrent use.
+type Store struct {
+	// offsets[i]:offsets[i+1] is the coded bucket for
+	// prefix index i.
+	offsets []uint64
+	data    []byte
+
+	entries  int
+	prefixes int
+
+	version string
+	built   time.Time
+}
+
+// Build creates a Store from the entries returned by r.
+//
+// r must be a dataset reader over a list that is ordered
+// by hash, as is the case for the ‘ordered by hash’
+// variants of the Pwned Passwords list. Entries with a
+// count of zero are skipped.
+func Build(r *passwords.Reader, opts ...Option) (*Store, error) {
+	s := &Store{
+		offsets: make([]uint64, prefix.Count+1),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var (
+		set  []byte
+		last = -1
+		next int
+	)
+	flush := func() {
+		for ; next <= last; next++ {
+			s.offsets[next] = uint64(len(s.data))
+		}
+
+		s.data = encode(s.data, set)
+		set = set[:0]
+		s.prefixes++
+	}
+
+	for r.Scan() {
+		pfx, suffix, count := r.Entry()
+		if count == 0 {
+			continue
+		}
+
+		idx, ok := prefix.Index(pfx)
+		if !ok {
+			return nil, fmt.Errorf("pwned/eliasfano: invalid prefix %q", pfx)
+		}
+
+		switch {
+		case idx < last:
+			return nil, errors.New("pwned/eliasfano: dataset is not ordered by hash")
+		case idx > last:
+			if last >= 0 {
+				flush()
+			}
+
+			last = idx
+		case bytes.Compare(suffix[:], set[len(set)-pwned.SuffixSize-1:len(set)-1]) <= 0:
+			return nil, errors.New("pwned/eliasfano: dataset is not ordered by hash")
+		}
+
+		set = pwned.AppendResult(set, suffix, count)
+		s.entries++
+	}
+
+	if r.Err() != nil {
+		return nil, fmt.Errorf("pwned/eliasfano: reader returned error: %v", r.Err())
+	}
+
+	if last >= 0 {
+		flush()
+	}
+
+	for ; next <= prefix.Count; next++ {
+		s.offsets[next] = uint64(len(s.data))
+	}
+
+	s.built = time.Now()
+	return s, nil
+}
+
+// Info implements pwned.InfoRanger.
+func (s *Store) Info(ctx context.Context) (*pwned.Info, error) {
+	return &pwned.Info{
+		Version:   s.version,
+		Entries:   uint64(s.entries),
+		Prefixes:  uint64(s.prefixes),
+		Algorithm: "SHA1",
+		Encoding:  "elias-fano",
+		Built:     s.built,
+	}, nil
+}
+
+// Len returns the number of entries in the Store.
+func (s *Store) Len() int {
+	return s.entries
+}
+
+// Size returns the number of bytes used to store the
+// coded buckets and their index.
+func (s *Store) Size() int {
+	return len(s.data) + 8*len(s.offsets)
+}
+
+func (s *Store) bucket(pfx string) (bucket, error) {
+	idx, ok := prefix.Index(pfx)
+	if !ok {
+		return bucket{}, errors.New("pwned/eliasfano: invalid prefix")
+	}
+
+	return decodeBucket(s.data[s.offsets[idx]:s.offsets[idx+1]]), nil
+}
+
+// Range implements pwned.Ranger.
+func (s *Store) Range(ctx context.Context, prefix string) ([]byte, error) {
+	b, err := s.bucket(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.appendSet(make([]byte, 0, pwned.Size(b.n)), prefix[pwned.PrefixSize-1]), nil
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (s *Store) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	b, err := s.bucket(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	return b.search(suffix), nil
+}
+
+// encode appends the Elias-Fano coding of set, which must
+// be sorted and in the pwned.AppendResult format, to dst.
+//
+// The coding is:
+//  n, uvarint;
+//  upper bits, n + 2^h bits;
+//  lower bits, n * (valueBits - h) bits;
+//  log2 counts, n bytes;
+// where h = floor(log2(n)). Empty buckets have no coding.
+func encode(dst, set []byte) []byte {
+	n := len(set) / (pwned.SuffixSize + 1)
+	if n == 0 {
+		return dst
+	}
+
+	b := newBucket(n)
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	dst = append(dst, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(n))]...)
+
+	start := len(dst)
+	dst = append(dst, make([]byte, b.size())...)
+	b.setData(dst[start:])
+
+	for i := 0; i < n; i++ {
+		entry := set[i*(pwned.SuffixSize+1):]
+
+		high := getBits(entry, 4, b.h)
+		putBits(b.upper, uint(high)+uint(i), 1, 1)
+		copyBits(b.lower, uint(i)*b.l, entry, 4+b.h, b.l)
+		b.counts[i] = entry[pwned.SuffixSize]
+	}
+
+	return dst
+}
+
+// bucket is a single decoded Elias-Fano coded bucket.
+type bucket struct {
+	n    int
+	h, l uint
+
+	upper, lower, counts []byte
+}
+
+func newBucket(n int) bucket {
+	h := uint(bits.Len(uint(n)) - 1)
+	return bucket{
+		n: n,
+		h: h,
+		l: valueBits - h,
+	}
+}
+
+func decodeBucket(data []byte) bucket {
+	if len(data) == 0 {
+		return bucket{}
+	}
+
+	n, sz := binary.Uvarint(data)
+	if sz <= 0 {
+		panic("pwned/eliasfano: invariant invalid bucket length")
+	}
+
+	b := newBucket(int(n))
+	b.setData(data[sz:])
+	return b
+}
+
+func (b *bucket) size() int {
+	return bitBytes(uint(b.n)+1<<b.h) + bitBytes(uint(b.n)*b.l) + b.n
+}
+
+func (b *bucket) setData(data []byte) {
+	if len(data) != b.size() {
+		panic("pwned/eliasfano: invariant invalid bucket size")
+	}
+
+	upper := bitBytes(uint(b.n) + 1<<b.h)
+	lower := bitBytes(uint(b.n) * b.l)
+
+	b.upper = data[:upper:upper]
+	b.lower = data[upper : upper+lower : upper+lower]
+	b.counts = data[upper+lower:]
+}
+
+// each calls fn with the index and high bits of each entry
+// in the bucket, in order, until fn returns false.
+func (b *bucket) each(fn func(i int, high uint64) bool) {
+	var high uint64
+	for i, pos := 0, uint(0); i < b.n; pos++ {
+		if readBits(b.upper, pos, 1) == 0 {
+			high++
+			continue
+		}
+
+		if !fn(i, high) {
+			return
+		}
+
+		i++
+	}
+}
+
+// suffix reconstructs the suffix of the i'th entry.
+func (b *bucket) suffix(nibble byte, i int, high uint64) (suffix [pwned.SuffixSize]byte) {
+	putBits(suffix[:], 0, uint64(nibble), 4)
+	putBits(suffix[:], 4, high, b.h)
+	copyBits(suffix[:], 4+b.h, b.lower, uint(i)*b.l, b.l)
+	return suffix
+}
+
+func (b *bucket) appendSet(dst []byte, last byte) []byte {
+	nibble := hexNibble(last)
+
+	b.each(func(i int, high uint64) bool {
+		suffix := b.suffix(nibble, i, high)
+		dst = append(dst, suffix[:]...)
+		dst = append(dst, b.counts[i])
+		return true
+	})
+
+	return dst
+}
+
+func (b *bucket) search(want [pwned.SuffixSize]byte) (count int) {
+	target := getBits(want[:], 4, b.h)
+
+	b.each(func(i int, high uint64) bool {
+		switch {
+		case high < target:
+			return true
+		case high > target:
+			return false
+		}
+
+		if b.suffix(want[0]>>4, i, high) != want {
+			return true
+		}
+
+		var set [pwned.SuffixSize + 1]byte
+		copy(set[:], want[:])
+		set[pwned.SuffixSize] = b.counts[i]
+		count = pwned.SearchSet(set[:], want)
+		return false
+	})
+
+	return count
+}
+
+func hexNibble(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// Option allows the Store to be configured when it is
+// built.
+type Option func(*Store)
+
+// WithVersion records the version of the dataset the
+// Store is built from, as reported by Info. For the Pwned
+// Passwords list this should be of the form "v8".
+func WithVersion(version string) Option {
+	return func(s *Store) {
+		s.version = version
+	}
+}
@@ -0,0 +1,212 @@
+package eliasfano
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/passwords"
+	"go.tmthrgd.dev/pwned/store/boltdb"
+)
+
+type entry struct {
+	digest [sha1.Size]byte
+	count  uint64
+}
+
+// testEntries returns n sorted random entries spread over
+// the given number of prefixes.
+func testEntries(n, prefixes int) []entry {
+	rand := rand.New(rand.NewSource(0))
+
+	pfxs := make([][3]byte, prefixes)
+	for i := range pfxs {
+		rand.Read(pfxs[i][:])
+	}
+
+	entries := make([]entry, n)
+	for i := range entries {
+		rand.Read(entries[i].digest[:])
+
+		pfx := pfxs[rand.Intn(prefixes)]
+		copy(entries[i].digest[:2], pfx[:2])
+		entries[i].digest[2] = pfx[2]&0xf0 | entries[i].digest[2]&0x0f
+
+		entries[i].count = uint64(rand.Int63n(1 << uint(rand.Intn(40))))
+		if entries[i].count == 0 {
+			entries[i].count = 1
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].digest[:], entries[j].digest[:]) < 0
+	})
+	return entries
+}
+
+func dataset(entries []entry) string {
+	var buf strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&buf, "%s:%d\r\n", strings.ToUpper(hex.EncodeToString(e.digest[:])), e.count)
+	}
+
+	return buf.String()
+}
+
+func sets(entries []entry) map[string][]byte {
+	sets := make(map[string][]byte)
+	for _, e := range entries {
+		prefix, suffix := pwned.SplitDigest(e.digest)
+		sets[prefix] = pwned.AppendResult(sets[prefix], suffix, e.count)
+	}
+
+	return sets
+}
+
+func TestRange(t *testing.T) {
+	t.Parallel()
+
+	entries := testEntries(20000, 20)
+
+	s, err := Build(passwords.NewDatasetReader(strings.NewReader(dataset(entries))), WithVersion("v8"))
+	require.NoError(t, err)
+	assert.Equal(t, len(entries), s.Len())
+
+	info, err := s.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v8", info.Version)
+	assert.Equal(t, uint64(len(entries)), info.Entries)
+	assert.Equal(t, uint64(20), info.Prefixes)
+
+	for prefix, set := range sets(entries) {
+		res, err := s.Range(context.Background(), prefix)
+		require.NoError(t, err)
+		assert.Equal(t, set, res, prefix)
+
+		res, err = s.Range(context.Background(), strings.ToUpper(prefix))
+		require.NoError(t, err)
+		assert.Equal(t, set, res, prefix)
+	}
+
+	res, err := s.Range(context.Background(), "00000")
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	_, err = s.Range(context.Background(), "0000g")
+	assert.Error(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	entries := testEntries(20000, 20)
+
+	s, err := Build(passwords.NewDatasetReader(strings.NewReader(dataset(entries))))
+	require.NoError(t, err)
+
+	for _, e := range entries {
+		_, suffix := pwned.SplitDigest(e.digest)
+		set := pwned.AppendResult(nil, suffix, e.count)
+
+		count, err := s.Lookup(context.Background(), e.digest)
+		require.NoError(t, err)
+		assert.Equal(t, pwned.SearchSet(set, suffix), count)
+
+		e.digest[sha1.Size-1]++
+
+		count, err = s.Lookup(context.Background(), e.digest)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	}
+}
+
+func TestBuildUnordered(t *testing.T) {
+	t.Parallel()
+
+	entries := testEntries(100, 2)
+	entries[10], entries[20] = entries[20], entries[10]
+
+	_, err := Build(passwords.NewDatasetReader(strings.NewReader(dataset(entries))))
+	assert.Error(t, err)
+}
+
+// benchmarkStores builds an Elias-Fano store and, for
+// comparison, an on-disk bolt store from the same entries.
+// The bolt store is memory mapped, so once its pages are
+// cached it is the fastest of the on-disk stores.
+func benchmarkStores(b *testing.B) (s *Store, db *boltdb.Store, entries []entry, cleanup func()) {
+	entries = testEntries(500*1024, 1024)
+	data := dataset(entries)
+
+	s, err := Build(passwords.NewDatasetReader(strings.NewReader(data)))
+	require.NoError(b, err)
+
+	dir, err := ioutil.TempDir("", "pwned-eliasfano")
+	require.NoError(b, err)
+
+	path := filepath.Join(dir, "pwned.db")
+	db, err = boltdb.Open(path, 0600)
+	require.NoError(b, err)
+
+	require.NoError(b, db.Import(passwords.NewDatasetReader(strings.NewReader(data))))
+
+	fi, err := os.Stat(path)
+	require.NoError(b, err)
+
+	b.Logf("N=%d -> Elias-Fano %d bytes (%.2f bytes/entry), pwned.AppendResult %d bytes (%.2f bytes/entry), bolt file %d bytes (%.2f bytes/entry)",
+		len(entries), len(s.data), float64(len(s.data))/float64(len(entries)),
+		pwned.Size(len(entries)), float64(pwned.Size(len(entries)))/float64(len(entries)),
+		fi.Size(), float64(fi.Size())/float64(len(entries)))
+	return s, db, entries, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func BenchmarkRange(b *testing.B) {
+	s, db, entries, cleanup := benchmarkStores(b)
+	defer cleanup()
+
+	prefix, _ := pwned.SplitDigest(entries[len(entries)/2].digest)
+
+	b.Run("eliasfano", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			s.Range(context.Background(), prefix)
+		}
+	})
+	b.Run("bolt", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			db.Range(context.Background(), prefix)
+		}
+	})
+}
+
+func BenchmarkLookup(b *testing.B) {
+	s, db, entries, cleanup := benchmarkStores(b)
+	defer cleanup()
+
+	digest := entries[len(entries)/2].digest
+
+	b.Run("eliasfano", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			s.Lookup(context.Background(), digest)
+		}
+	})
+	b.Run("bolt", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			db.Lookup(context.Background(), digest)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+// Package truncated provides an in-memory password store
+// that keeps only the leading bits of each hash.
+//
+// A blacklist rarely needs all 160 bits of each SHA1 hash.
+// Keeping only the first K bits of each hash reduces the
+// size of every entry to K/8 - 2 bytes, plus one byte for
+// the count, at the cost of false positives: a password
+// that is not in the list will match an entry that is
+// with probability of approximately N/2^K, where N is the
+// number of entries.
+//
+// For the roughly 847 million entries in version 8 of the
+// Pwned Passwords list, this gives:
+//  K=32,   3 bytes/entry: 1.8e-1;
+//  K=40,   4 bytes/entry: 7.7e-4;
+//  K=48,   5 bytes/entry: 3.0e-6;
+//  K=56,   6 bytes/entry: 1.2e-8;
+//  K=64,   7 bytes/entry: 4.6e-11;
+//  K=80,   9 bytes/entry: 7.0e-16;
+//  K=160, 19 bytes/entry: none.
+// FalsePositiveRate computes this for other lists.
+package truncated
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/internal/prefix"
+	"go.tmthrgd.dev/pwned/passwords"
+)
+
+// FalsePositiveRate returns the probability that a hash
+// which is not in a list of the given number of entries
+// matches one that is, when the hashes are truncated to
+// hashBits bits.
+func FalsePositiveRate(entries int, hashBits int) float64 {
+	return -math.Expm1(float64(entries) * math.Log1p(-math.Exp2(-float64(hashBits))))
+}
+
+// Store is a password store of truncated hashes. It
+// implements pwned.Ranger, pwnedgrpc.Lookup and
+// pwnedgrpc.Truncated.
+//
+// A Store is safe for concurrent use.
+type Store struct {
+	hashBits int
+	size     int
+
+	// offsets[i]:offsets[i+1] is the result set for
+	// prefix index i.
+	offsets []uint64
+	data    []byte
+
+	entries  int
+	prefixes int
+
+	version string
+	built   time.Time
+}
+
+// Build creates a Store from the entries returned by r,
+// truncating each hash to hashBits bits. hashBits must be
+// a multiple of eight between 32 and 160.
+//
+// r must be a dataset reader over a list that is ordered
+// by hash, as is the case for the ‘ordered by hash’
+// variants of the Pwned Passwords list. Entries with a
+// count of zero are skipped. Entries that are identical
+// once truncated are merged and their counts summed.
+func Build(r *passwords.Reader, hashBits int, opts ...Option) (*Store, error) {
+	if hashBits%8 != 0 || hashBits < 32 || hashBits > 8*sha1.Size {
+		return nil, errors.New("pwned/truncated: invalid hash size")
+	}
+
+	s := &Store{
+		hashBits: hashBits,
+		size:     pwned.TruncatedSuffixSize(hashBits),
+
+		offsets: make([]uint64, prefix.Count+1),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var (
+		last      = -1
+		next      int
+		lastEntry [pwned.SuffixSize]byte
+		lastCount uint64
+	)
+	flush := func() {
+		if next <= last {
+			s.prefixes++
+		}
+
+		for ; next <= last; next++ {
+			s.offsets[next] = uint64(len(s.data))
+		}
+
+		s.data = pwned.AppendTruncatedResult(s.data, lastEntry, hashBits, lastCount)
+		s.entries++
+	}
+
+	for r.Scan() {
+		pfx, suffix, count := r.Entry()
+		if count == 0 {
+			continue
+		}
+
+		idx, ok := prefix.Index(pfx)
+		if !ok {
+			return nil, fmt.Errorf("pwned/truncated: invalid prefix %q", pfx)
+		}
+
+		if idx < last || idx == last && bytes.Compare(suffix[:], lastEntry[:]) <= 0 {
+			return nil, errors.New("pwned/truncated: dataset is not ordered by hash")
+		}
+
+		if idx == last && bytes.Equal(suffix[:s.size], lastEntry[:s.size]) {
+			lastEntry = suffix
+
+			if lastCount += count; lastCount < count {
+				lastCount = math.MaxUint64
+			}
+
+			continue
+		}
+
+		if last >= 0 {
+			flush()
+		}
+
+		last, lastEntry, lastCount = idx, suffix, count
+	}
+
+	if r.Err() != nil {
+		return nil, fmt.Errorf("pwned/truncated: reader returned error: %v", r.Err())
+	}
+
+	if last >= 0 {
+		flush()
+	}
+
+	for ; next <= prefix.Count; next++ {
+		s.offsets[next] = uint64(len(s.data))
+	}
+
+	s.built = time.Now()
+	return s, nil
+}
+
+// Info implements pwned.InfoRanger.
+func (s *Store) Info(ctx context.Context) (*pwned.Info, error) {
+	return &pwned.Info{
+		Version:   s.version,
+		Entries:   uint64(s.entries),
+		Prefixes:  uint64(s.prefixes),
+		Algorithm: "SHA1",
+		Encoding:  "truncated",
+		Built:     s.built,
+	}, nil
+}
+
+// Len returns the number of entries in the Store.
+func (s *Store) Len() int {
+	return s.entries
+}
+
+// Size returns the number of bytes used to store the
+// entries and their index.
+func (s *Store) Size() int {
+	return len(s.data) + 8*len(s.offsets)
+}
+
+// TruncatedBits implements pwnedgrpc.Truncated.
+func (s *Store) TruncatedBits() int {
+	return s.hashBits
+}
+
+func (s *Store) set(pfx string) ([]byte, error) {
+	idx, ok := prefix.Index(pfx)
+	if !ok {
+		return nil, errors.New("pwned/truncated: invalid prefix")
+	}
+
+	return s.data[s.offsets[idx]:s.offsets[idx+1]], nil
+}
+
+// Range implements pwned.Ranger. The returned results
+// contain truncated suffixes and must be searched with
+// pwned.SearchTruncatedSet.
+func (s *Store) Range(ctx context.Context, prefix string) ([]byte, error) {
+	set, err := s.set(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), set...), nil
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (s *Store) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	set, err := s.set(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	want := suffix[:s.size]
+	n := len(set) / (s.size + 1)
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(set[i*(s.size+1):i*(s.size+1)+s.size], want) >= 0
+	})
+	if i == n {
+		return 0, nil
+	}
+
+	return pwned.SearchTruncatedSet(set[i*(s.size+1):(i+1)*(s.size+1)], suffix, s.hashBits), nil
+}
+
+// Option allows the Store to be configured when it is
+// built.
+type Option func(*Store)
+
+// WithVersion records the version of the dataset the
+// Store is built from, as reported by Info. For the Pwned
+// Passwords list this should be of the form "v8".
+func WithVersion(version string) Option {
+	return func(s *Store) {
+		s.version = version
+	}
+}
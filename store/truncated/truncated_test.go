@@ -0,0 +1,103 @@
+package truncated
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/passwords"
+)
+
+var testPasswords = []string{
+	"password", "P@ssw0rd", "lauragpe", "alexguo029",
+	"BDnd9102", "melobie", "quvekyny",
+}
+
+func dataset(passwords ...string) string {
+	lines := make([]string, len(passwords))
+	for i, password := range passwords {
+		digest := sha1.Sum([]byte(password))
+		lines[i] = fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(digest[:])), i+1)
+	}
+
+	sort.Strings(lines)
+	return strings.Join(lines, "\r\n")
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	for _, hashBits := range []int{32, 64, 160} {
+		s, err := Build(passwords.NewDatasetReader(strings.NewReader(dataset(testPasswords...))), hashBits)
+		require.NoError(t, err)
+		assert.Equal(t, len(testPasswords), s.Len())
+
+		for i, password := range testPasswords {
+			count, err := s.Lookup(context.Background(), sha1.Sum([]byte(password)))
+			require.NoError(t, err)
+			assert.Equal(t, 1<<uint(bits.Len(uint(i+1))-1), count)
+		}
+
+		count, err := s.Lookup(context.Background(), sha1.Sum([]byte("correct horse battery staple")))
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	}
+}
+
+func TestRange(t *testing.T) {
+	t.Parallel()
+
+	s, err := Build(passwords.NewDatasetReader(strings.NewReader(dataset(testPasswords...))), 64)
+	require.NoError(t, err)
+
+	prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte("password")))
+
+	set, err := s.Range(context.Background(), prefix)
+	require.NoError(t, err)
+	assert.Equal(t, pwned.AppendTruncatedResult(nil, suffix, 64, 1), set)
+	assert.Equal(t, 1, pwned.SearchTruncatedSet(set, suffix, 64))
+}
+
+func TestBuildMerges(t *testing.T) {
+	t.Parallel()
+
+	data := "0000000000000000000000000000000000000001:1\n" +
+		"0000000000000000000000000000000000000002:2\n" +
+		"0000000100000000000000000000000000000000:4\n"
+
+	s, err := Build(passwords.NewDatasetReader(strings.NewReader(data)), 32)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+
+	count, err := s.Lookup(context.Background(), [sha1.Size]byte{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestBuildInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Build(passwords.NewDatasetReader(strings.NewReader(dataset(testPasswords...))), 36)
+	assert.Error(t, err)
+
+	data := "0000000000000000000000000000000000000002:1\n" +
+		"0000000000000000000000000000000000000001:1\n"
+
+	_, err = Build(passwords.NewDatasetReader(strings.NewReader(data)), 64)
+	assert.Error(t, err)
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 4.6e-11, FalsePositiveRate(847e6, 64), 0.05e-11)
+	assert.InDelta(t, 0.179, FalsePositiveRate(847e6, 32), 0.001)
+}
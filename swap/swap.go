@@ -0,0 +1,398 @@
+// Package swap provides a pwned.Ranger whose source can be
+// atomically replaced while it is in use.
+package swap
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+// Validator checks that a new source is usable before it
+// replaces the active source.
+type Validator func(ctx context.Context, r pwned.Ranger) error
+
+// Validate is the default Validator. It performs a single
+// range query for the prefix 5BAA6 and checks that it
+// succeeds and returns a well formed result set.
+//
+// For remote sources, such as the Pwned Passwords API, this
+// is a network request. ValidatePrefix, or WithValidator,
+// may be used to choose a different probe.
+func Validate(ctx context.Context, r pwned.Ranger) error {
+	return validatePrefix(ctx, r, "5BAA6")
+}
+
+// ValidatePrefix returns a Validator that is like Validate
+// except that it queries the given prefix.
+func ValidatePrefix(prefix string) Validator {
+	return func(ctx context.Context, r pwned.Ranger) error {
+		return validatePrefix(ctx, r, prefix)
+	}
+}
+
+func validatePrefix(ctx context.Context, r pwned.Ranger, prefix string) error {
+	set, err := r.Range(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("pwned/swap: validation range query failed: %v", err)
+	}
+
+	if len(set)%(suffixSize(truncatedBits(r))+1) != 0 {
+		return errors.New("pwned/swap: validation range query returned invalid result set")
+	}
+
+	return nil
+}
+
+func truncatedBits(r pwned.Ranger) int {
+	if t, ok := r.(pwnedgrpc.Truncated); ok {
+		return t.TruncatedBits()
+	}
+
+	return 0
+}
+
+func suffixSize(truncatedBits int) int {
+	if truncatedBits != 0 {
+		return pwned.TruncatedSuffixSize(truncatedBits)
+	}
+
+	return pwned.SuffixSize
+}
+
+type source struct {
+	ranger pwned.Ranger
+
+	// refs counts the calls that are using ranger.
+	refs sync.WaitGroup
+
+	// reopened is non-nil while Reopen is replacing the
+	// source, and is closed once it has been replaced.
+	reopened chan struct{}
+}
+
+// Ranger is a pwned.Ranger that forwards to a source that
+// can be replaced with Swap. It also implements
+// pwnedgrpc.Lookup, pwnedgrpc.Truncated,
+// pwnedgrpc.OptionalCache and pwned.InfoRanger, forwarding
+// to the source when it implements them.
+//
+// Every source must have the same pwnedgrpc.Truncated
+// hash size as the initial source.
+//
+// A Ranger is safe for concurrent use.
+type Ranger struct {
+	validate      Validator
+	truncatedBits int
+
+	swapMu sync.Mutex
+
+	mu  sync.RWMutex
+	cur *source
+}
+
+// New returns a Ranger with r as its initial source. r is
+// not validated.
+func New(r pwned.Ranger, opts ...Option) *Ranger {
+	s := &Ranger{
+		validate:      Validate,
+		truncatedBits: truncatedBits(r),
+
+		cur: &source{ranger: r},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// acquire returns the active source, waiting for Reopen
+// to replace it if necessary. release must be called once
+// it is no longer in use.
+func (s *Ranger) acquire(ctx context.Context) (*source, error) {
+	for {
+		s.mu.RLock()
+		src := s.cur
+		if src.reopened == nil {
+			src.refs.Add(1)
+			s.mu.RUnlock()
+			return src, nil
+		}
+		s.mu.RUnlock()
+
+		select {
+		case <-src.reopened:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (src *source) release() {
+	src.refs.Done()
+}
+
+// Swap validates r and, if it is valid, makes it the active
+// source. Calls that are already using the old source are
+// allowed to finish, after which the old source is closed
+// if it implements io.Closer. Swap does not return until
+// the old source has been closed.
+//
+// If r fails validation, the old source remains active and
+// r is not closed.
+func (s *Ranger) Swap(ctx context.Context, r pwned.Ranger) error {
+	if err := s.check(ctx, r); err != nil {
+		return err
+	}
+
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+
+	s.mu.Lock()
+	old := s.cur
+	s.cur = &source{ranger: r}
+	s.mu.Unlock()
+
+	old.refs.Wait()
+
+	if c, ok := old.ranger.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("pwned/swap: failed to close old source: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Reopen closes the active source and replaces it with the
+// one returned by open. It is for sources, such as bbolt
+// databases, that cannot be opened again while they are
+// still open. Calls that are already using the old source
+// are allowed to finish before it is closed, and new calls
+// wait until the source has been reopened.
+//
+// If open fails, or the new source fails validation, every
+// call returns an error until the next successful Swap or
+// Reopen.
+func (s *Ranger) Reopen(ctx context.Context, open func() (pwned.Ranger, error)) error {
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+
+	// New calls wait on the placeholder until the new
+	// source is published, without holding mu.
+	placeholder := &source{reopened: make(chan struct{})}
+
+	s.mu.Lock()
+	old := s.cur
+	s.cur = placeholder
+	s.mu.Unlock()
+
+	r, err := s.reopen(ctx, old, open)
+	if err != nil {
+		r = failed{err}
+	}
+
+	s.mu.Lock()
+	s.cur = &source{ranger: r}
+	s.mu.Unlock()
+
+	close(placeholder.reopened)
+	return err
+}
+
+// reopen closes old, once it is no longer in use, and
+// returns the validated source returned by open.
+func (s *Ranger) reopen(ctx context.Context, old *source, open func() (pwned.Ranger, error)) (pwned.Ranger, error) {
+	old.refs.Wait()
+
+	if c, ok := old.ranger.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return nil, fmt.Errorf("pwned/swap: failed to close old source: %v", err)
+		}
+	}
+
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.check(ctx, r); err != nil {
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// check checks that r can replace the active source.
+func (s *Ranger) check(ctx context.Context, r pwned.Ranger) error {
+	if truncatedBits(r) != s.truncatedBits {
+		return errors.New("pwned/swap: source has a different truncated hash size")
+	}
+
+	return s.validate(ctx, r)
+}
+
+// failed is the source left by a failed Reopen.
+type failed struct{ err error }
+
+func (f failed) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return nil, fmt.Errorf("pwned/swap: source could not be reopened: %v", f.err)
+}
+
+// Current returns the active source, waiting for Reopen if
+// necessary. It may be closed at any time by Swap or
+// Reopen.
+func (s *Ranger) Current() pwned.Ranger {
+	src, _ := s.acquire(context.Background())
+	defer src.release()
+	return src.ranger
+}
+
+// Range implements pwned.Ranger.
+func (s *Ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	src, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer src.release()
+
+	return src.ranger.Range(ctx, prefix)
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (s *Ranger) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	src, err := s.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer src.release()
+
+	if l, ok := src.ranger.(pwnedgrpc.Lookup); ok {
+		return l.Lookup(ctx, digest)
+	}
+
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	res, err := src.ranger.Range(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(res)%(suffixSize(s.truncatedBits)+1) != 0 {
+		return 0, errors.New("pwned/swap: invalid result set returned")
+	}
+
+	if s.truncatedBits != 0 {
+		return pwned.SearchTruncatedSet(res, suffix, s.truncatedBits), nil
+	}
+
+	return pwned.SearchSet(res, suffix), nil
+}
+
+// TruncatedBits implements pwnedgrpc.Truncated. It returns
+// zero if the sources are not truncated.
+func (s *Ranger) TruncatedBits() int {
+	return s.truncatedBits
+}
+
+// Info implements pwned.InfoRanger. If the active source
+// does not implement pwned.InfoRanger, pwned.ErrNoInfo is
+// returned.
+func (s *Ranger) Info(ctx context.Context) (*pwned.Info, error) {
+	src, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer src.release()
+
+	if ir, ok := src.ranger.(pwned.InfoRanger); ok {
+		return ir.Info(ctx)
+	}
+
+	return nil, pwned.ErrNoInfo
+}
+
+// HasCache implements pwnedgrpc.OptionalCache. It reports
+// whether the active source implements pwnedgrpc.Cache and
+// has a cache.
+func (s *Ranger) HasCache() bool {
+	src, _ := s.acquire(context.Background())
+	defer src.release()
+
+	c, ok := src.ranger.(pwnedgrpc.Cache)
+	if oc, optional := c.(pwnedgrpc.OptionalCache); optional {
+		return oc.HasCache()
+	}
+
+	return ok
+}
+
+// Purge implements pwnedgrpc.Cache. It does nothing if the
+// active source does not implement pwnedgrpc.Cache.
+func (s *Ranger) Purge() int {
+	src, _ := s.acquire(context.Background())
+	defer src.release()
+
+	if c, ok := src.ranger.(pwnedgrpc.Cache); ok {
+		return c.Purge()
+	}
+
+	return 0
+}
+
+// CacheStats implements pwnedgrpc.Cache. It returns the
+// statistics of the active source, or zero if it does not
+// implement pwnedgrpc.Cache.
+func (s *Ranger) CacheStats() (hits, misses uint64) {
+	src, _ := s.acquire(context.Background())
+	defer src.release()
+
+	if c, ok := src.ranger.(pwnedgrpc.Cache); ok {
+		return c.CacheStats()
+	}
+
+	return 0, 0
+}
+
+// Close closes the active source if it implements
+// io.Closer. The Ranger must not be used afterwards.
+func (s *Ranger) Close() error {
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+
+	s.mu.RLock()
+	src := s.cur
+	s.mu.RUnlock()
+
+	src.refs.Wait()
+
+	if c, ok := src.ranger.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// Option allows the behaviour of the Ranger to be
+// configured.
+type Option func(*Ranger)
+
+// WithValidator sets the Validator that Swap uses to check
+// new sources. It defaults to Validate.
+func WithValidator(fn Validator) Option {
+	return func(s *Ranger) {
+		s.validate = fn
+	}
+}
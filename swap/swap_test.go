@@ -0,0 +1,210 @@
+package swap
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+)
+
+type ranger struct {
+	set     []byte
+	entered chan struct{}
+	block   chan struct{}
+	closed  int32
+	invalid bool
+}
+
+func newRanger(password string, count uint64) *ranger {
+	_, suffix := pwned.SplitDigest(sha1.Sum([]byte(password)))
+	return &ranger{set: pwned.AppendResult(nil, suffix, count)}
+}
+
+func (r *ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	if r.block != nil {
+		r.entered <- struct{}{}
+		<-r.block
+	}
+
+	if r.invalid {
+		return nil, errors.New("invalid")
+	}
+
+	if atomic.LoadInt32(&r.closed) != 0 {
+		return nil, errors.New("closed")
+	}
+
+	return r.set, nil
+}
+
+func (r *ranger) Close() error {
+	atomic.StoreInt32(&r.closed, 1)
+	return nil
+}
+
+func lookup(t *testing.T, s *Ranger) int {
+	count, err := s.Lookup(context.Background(), sha1.Sum([]byte("password")))
+	require.NoError(t, err)
+	return count
+}
+
+func TestSwap(t *testing.T) {
+	t.Parallel()
+
+	old := newRanger("password", 8)
+	s := New(old)
+	assert.Equal(t, 8, lookup(t, s))
+
+	assert.Error(t, s.Swap(context.Background(), &ranger{invalid: true}))
+	assert.Equal(t, 8, lookup(t, s))
+
+	next := newRanger("password", 16)
+	require.NoError(t, s.Swap(context.Background(), next))
+	assert.Equal(t, 16, lookup(t, s))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&old.closed))
+
+	require.NoError(t, s.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&next.closed))
+}
+
+func TestSwapInFlight(t *testing.T) {
+	t.Parallel()
+
+	old := newRanger("password", 8)
+	old.entered = make(chan struct{})
+	old.block = make(chan struct{})
+	s := New(old)
+
+	inFlight := make(chan error)
+	go func() {
+		_, err := s.Range(context.Background(), "5baa6")
+		inFlight <- err
+	}()
+
+	<-old.entered
+
+	swapped := make(chan error)
+	go func() {
+		swapped <- s.Swap(context.Background(), newRanger("password", 16))
+	}()
+
+	select {
+	case <-swapped:
+		t.Fatal("Swap returned before in-flight call finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.Equal(t, 16, lookup(t, s))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&old.closed))
+
+	close(old.block)
+	require.NoError(t, <-inFlight)
+	require.NoError(t, <-swapped)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&old.closed))
+}
+
+func TestReopen(t *testing.T) {
+	t.Parallel()
+
+	old := newRanger("password", 8)
+	s := New(old)
+
+	require.NoError(t, s.Reopen(context.Background(), func() (pwned.Ranger, error) {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&old.closed), "old source open during reopen")
+		return newRanger("password", 16), nil
+	}))
+	assert.Equal(t, 16, lookup(t, s))
+
+	assert.Error(t, s.Reopen(context.Background(), func() (pwned.Ranger, error) {
+		return nil, errors.New("failed")
+	}))
+
+	_, err := s.Range(context.Background(), "5baa6")
+	assert.Error(t, err)
+
+	require.NoError(t, s.Reopen(context.Background(), func() (pwned.Ranger, error) {
+		return newRanger("password", 32), nil
+	}))
+	assert.Equal(t, 32, lookup(t, s))
+}
+
+func TestReopenWaits(t *testing.T) {
+	t.Parallel()
+
+	s := New(newRanger("password", 8))
+
+	opening, open := make(chan struct{}), make(chan struct{})
+	reopened := make(chan error)
+	go func() {
+		reopened <- s.Reopen(context.Background(), func() (pwned.Ranger, error) {
+			close(opening)
+			<-open
+			return newRanger("password", 16), nil
+		})
+	}()
+
+	<-opening
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := s.Range(ctx, "5baa6")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	waiting := make(chan int)
+	go func() { waiting <- lookup(t, s) }()
+
+	select {
+	case <-waiting:
+		t.Fatal("Lookup returned before Reopen finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(open)
+	require.NoError(t, <-reopened)
+	assert.Equal(t, 16, <-waiting)
+}
+
+func TestValidatePrefix(t *testing.T) {
+	t.Parallel()
+
+	s := New(newRanger("password", 8), WithValidator(ValidatePrefix("00000")))
+	require.NoError(t, s.Swap(context.Background(), newRanger("password", 16)))
+	assert.Error(t, s.Swap(context.Background(), &ranger{invalid: true}))
+}
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(newRanger("password", 8)).Info(context.Background())
+	assert.Equal(t, pwned.ErrNoInfo, err)
+}
+
+type cached struct {
+	*ranger
+}
+
+func (cached) Purge() int {
+	return 1
+}
+
+func (cached) CacheStats() (hits, misses uint64) {
+	return 2, 3
+}
+
+func TestHasCache(t *testing.T) {
+	t.Parallel()
+
+	s := New(newRanger("password", 8))
+	assert.False(t, s.HasCache())
+
+	require.NoError(t, s.Swap(context.Background(), cached{newRanger("password", 8)}))
+	assert.True(t, s.HasCache())
+	assert.Equal(t, 1, s.Purge())
+}
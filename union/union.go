@@ -0,0 +1,295 @@
+// Package union provides a pwned.Ranger that merges the
+// results of several other Rangers.
+package union
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+// Combine merges the estimated counts of a suffix that is
+// present in the results of more than one Ranger.
+type Combine func(a, b uint64) uint64
+
+// Max is a Combine that takes the larger of the two
+// counts.
+func Max(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// Sum is a Combine that adds the two counts together. It
+// saturates rather than overflowing.
+func Sum(a, b uint64) uint64 {
+	if a+b < a {
+		return math.MaxUint64
+	}
+
+	return a + b
+}
+
+type union struct {
+	combine       Combine
+	rangers       []pwned.Ranger
+	truncatedBits int
+}
+
+type lookupUnion struct {
+	union
+	lookups []pwnedgrpc.Lookup
+}
+
+// New returns a pwned.Ranger that queries each of the
+// given Rangers concurrently and merges their results.
+// Suffixes that are returned by more than one Ranger are
+// merged with combine.
+//
+// If all of the Rangers implement pwnedgrpc.Lookup, the
+// returned Ranger will also implement it. It always
+// implements pwnedgrpc.Truncated and pwned.InfoRanger.
+//
+// The Rangers must return results in the format produced
+// by pwned.AppendResult, or pwned.AppendTruncatedResult if
+// they implement pwnedgrpc.Truncated, in which case they
+// must all have the same truncated hash size. New panics
+// if they do not. As the results only contain an estimate
+// of each count, the combined counts are themselves
+// estimates.
+func New(combine Combine, rangers ...pwned.Ranger) pwned.Ranger {
+	u := union{combine: combine, rangers: rangers}
+
+	for i, r := range rangers {
+		var bits int
+		if t, ok := r.(pwnedgrpc.Truncated); ok {
+			bits = t.TruncatedBits()
+		}
+
+		if i == 0 {
+			u.truncatedBits = bits
+		} else if bits != u.truncatedBits {
+			panic("pwned/union: rangers have different truncated hash sizes")
+		}
+	}
+
+	lookups := make([]pwnedgrpc.Lookup, 0, len(rangers))
+	for _, r := range rangers {
+		l, ok := r.(pwnedgrpc.Lookup)
+		if !ok {
+			return &u
+		}
+
+		lookups = append(lookups, l)
+	}
+
+	return &lookupUnion{u, lookups}
+}
+
+// each calls fn concurrently for each of n Rangers. It
+// returns the first error encountered and cancels the
+// context passed to the other calls.
+func each(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if e := fn(ctx, i); e != nil {
+				once.Do(func() {
+					err = fmt.Errorf("pwned/union: ranger %d returned error: %v", i, e)
+					cancel()
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return err
+}
+
+func (u *union) Range(ctx context.Context, prefix string) ([]byte, error) {
+	sets := make([][]byte, len(u.rangers))
+	if err := each(ctx, len(u.rangers), func(ctx context.Context, i int) (err error) {
+		sets[i], err = u.rangers[i].Range(ctx, prefix)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	size := pwned.SuffixSize
+	if u.truncatedBits != 0 {
+		size = pwned.TruncatedSuffixSize(u.truncatedBits)
+	}
+
+	// Truncated suffixes are padded with zeros.
+	counts := make(map[[pwned.SuffixSize]byte]uint64)
+	for i, set := range sets {
+		if len(set)%(size+1) != 0 {
+			return nil, fmt.Errorf("pwned/union: ranger %d returned invalid result set", i)
+		}
+
+		for ; len(set) > 0; set = set[size+1:] {
+			var suffix [pwned.SuffixSize]byte
+			copy(suffix[:], set[:size])
+
+			count := estimate(set[size])
+			if old, ok := counts[suffix]; ok {
+				count = u.combine(old, count)
+			}
+
+			counts[suffix] = count
+		}
+	}
+
+	suffixes := make([][pwned.SuffixSize]byte, 0, len(counts))
+	for suffix := range counts {
+		suffixes = append(suffixes, suffix)
+	}
+
+	sort.Slice(suffixes, func(i, j int) bool {
+		return bytes.Compare(suffixes[i][:], suffixes[j][:]) < 0
+	})
+
+	res := make([]byte, 0, len(suffixes)*(size+1))
+	for _, suffix := range suffixes {
+		if u.truncatedBits != 0 {
+			res = pwned.AppendTruncatedResult(res, suffix, u.truncatedBits, counts[suffix])
+		} else {
+			res = pwned.AppendResult(res, suffix, counts[suffix])
+		}
+	}
+
+	return res, nil
+}
+
+// TruncatedBits implements pwnedgrpc.Truncated. It returns
+// the truncated hash size shared by the Rangers, or zero if
+// they are not truncated.
+func (u *union) TruncatedBits() int {
+	return u.truncatedBits
+}
+
+// Info implements pwned.InfoRanger. The versions of the
+// Rangers that implement pwned.InfoRanger are joined with
+// '+', and Built is the most recent of their build times.
+// As the Rangers may overlap, the number of entries and
+// prefixes is not known. If none of the Rangers implement
+// pwned.InfoRanger, pwned.ErrNoInfo is returned.
+func (u *union) Info(ctx context.Context) (*pwned.Info, error) {
+	var (
+		versions  []string
+		algorithm string
+		built     time.Time
+		found     bool
+	)
+	for _, r := range u.rangers {
+		ir, ok := r.(pwned.InfoRanger)
+		if !ok {
+			continue
+		}
+
+		info, err := ir.Info(ctx)
+		if err == pwned.ErrNoInfo {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		if info.Version != "" {
+			versions = append(versions, info.Version)
+		}
+		if algorithm == "" {
+			algorithm = info.Algorithm
+		}
+		if info.Built.After(built) {
+			built = info.Built
+		}
+	}
+
+	if !found {
+		return nil, pwned.ErrNoInfo
+	}
+
+	return &pwned.Info{
+		Version:   strings.Join(versions, "+"),
+		Algorithm: algorithm,
+		Encoding:  "union",
+		Built:     built,
+	}, nil
+}
+
+func (u *lookupUnion) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	counts := make([]int, len(u.lookups))
+	if err := each(ctx, len(u.lookups), func(ctx context.Context, i int) (err error) {
+		counts[i], err = u.lookups[i].Lookup(ctx, digest)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	var (
+		total uint64
+		found bool
+	)
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+
+		if found {
+			total = u.combine(total, uint64(c))
+		} else {
+			total, found = uint64(c), true
+		}
+	}
+
+	if !found {
+		return 0, nil
+	}
+
+	// Round down to a power of two as Range would.
+	n := 63 - bits.LeadingZeros64(total)
+	if n > bits.UintSize-2 {
+		const maxInt = int(^uint(0) >> 1)
+		return maxInt, nil
+	}
+
+	return 1 << uint(n), nil
+}
+
+// estimate returns the count that is encoded by the log2
+// count found in a result set. pwned.AppendResult encodes a
+// count of zero as 255.
+func estimate(logCount byte) uint64 {
+	switch {
+	case logCount == 255:
+		return 0
+	case logCount > 63:
+		return math.MaxUint64
+	}
+
+	return 1 << logCount
+}
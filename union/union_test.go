@@ -0,0 +1,180 @@
+package union
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+)
+
+type ranger map[string][]byte
+
+func newRanger(counts map[string]uint64) ranger {
+	r := make(ranger)
+	for password, count := range counts {
+		prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte(password)))
+		r[prefix] = pwned.AppendResult(r[prefix], suffix, count)
+	}
+
+	return r
+}
+
+func (r ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return r[prefix], nil
+}
+
+type lookupRanger struct{ ranger }
+
+func (r lookupRanger) Lookup(ctx context.Context, digest [sha1.Size]byte) (int, error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+	return pwned.SearchSet(r.ranger[prefix], suffix), nil
+}
+
+type errRanger struct{}
+
+func (errRanger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func search(t *testing.T, r pwned.Ranger, password string) int {
+	prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte(password)))
+
+	set, err := r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+
+	return pwned.SearchSet(set, suffix)
+}
+
+func TestRange(t *testing.T) {
+	t.Parallel()
+
+	a := newRanger(map[string]uint64{"password": 8, "P@ssw0rd": 2})
+	b := newRanger(map[string]uint64{"password": 4, "lauragpe": 1})
+
+	max := New(Max, a, b)
+	assert.Equal(t, 8, search(t, max, "password"))
+	assert.Equal(t, 2, search(t, max, "P@ssw0rd"))
+	assert.Equal(t, 1, search(t, max, "lauragpe"))
+	assert.Equal(t, 0, search(t, max, "melobie"))
+
+	sum := New(Sum, a, b)
+	assert.Equal(t, 8, search(t, sum, "password"))
+
+	sum = New(Sum, a, b, a)
+	assert.Equal(t, 16, search(t, sum, "password"))
+	assert.Equal(t, 4, search(t, sum, "P@ssw0rd"))
+
+	_, ok := max.(pwnedgrpc.Lookup)
+	assert.False(t, ok)
+
+	_, err := New(Max, a, errRanger{}).Range(context.Background(), "00000")
+	assert.Error(t, err)
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	a := lookupRanger{newRanger(map[string]uint64{"password": 8, "P@ssw0rd": 2})}
+	b := lookupRanger{newRanger(map[string]uint64{"password": 8, "lauragpe": 1})}
+
+	u, ok := New(Sum, a, b).(pwnedgrpc.Lookup)
+	require.True(t, ok)
+
+	for password, want := range map[string]int{
+		"password": 16,
+		"P@ssw0rd": 2,
+		"lauragpe": 1,
+		"melobie":  0,
+	} {
+		count, err := u.Lookup(context.Background(), sha1.Sum([]byte(password)))
+		require.NoError(t, err)
+		assert.Equal(t, want, count, password)
+		assert.Equal(t, want, search(t, u, password), password)
+	}
+}
+
+type truncatedRanger struct {
+	ranger
+	bits int
+}
+
+func newTruncatedRanger(counts map[string]uint64, bits int) truncatedRanger {
+	r := truncatedRanger{make(ranger), bits}
+	for password, count := range counts {
+		prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte(password)))
+		r.ranger[prefix] = pwned.AppendTruncatedResult(r.ranger[prefix], suffix, bits, count)
+	}
+
+	return r
+}
+
+func (r truncatedRanger) TruncatedBits() int { return r.bits }
+
+type infoRanger struct {
+	ranger
+	info *pwned.Info
+}
+
+func (r infoRanger) Info(ctx context.Context) (*pwned.Info, error) {
+	return r.info, nil
+}
+
+func TestZeroCount(t *testing.T) {
+	t.Parallel()
+
+	a := newRanger(map[string]uint64{"password": 0})
+	b := newRanger(map[string]uint64{"password": 4})
+
+	assert.Equal(t, 4, search(t, New(Sum, a, b), "password"))
+	assert.Equal(t, 4, search(t, New(Max, a, b), "password"))
+}
+
+func TestTruncated(t *testing.T) {
+	t.Parallel()
+
+	a := newTruncatedRanger(map[string]uint64{"password": 8, "P@ssw0rd": 2}, 64)
+	b := newTruncatedRanger(map[string]uint64{"password": 4}, 64)
+
+	u := New(Sum, a, b)
+	assert.Equal(t, 64, u.(pwnedgrpc.Truncated).TruncatedBits())
+
+	count, err := pwned.Search(context.Background(), u, "password")
+	require.NoError(t, err)
+	assert.Equal(t, 8, count)
+
+	count, err = pwned.Search(context.Background(), u, "P@ssw0rd")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	full := newRanger(map[string]uint64{"password": 4})
+	assert.Equal(t, 0, New(Sum, full, full).(pwnedgrpc.Truncated).TruncatedBits())
+
+	assert.Panics(t, func() { New(Sum, a, full) })
+	assert.Panics(t, func() { New(Sum, a, newTruncatedRanger(nil, 96)) })
+}
+
+func TestInfo(t *testing.T) {
+	t.Parallel()
+
+	built := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	a := infoRanger{newRanger(nil), &pwned.Info{Version: "v5", Algorithm: "SHA1", Built: built.AddDate(-1, 0, 0)}}
+	b := infoRanger{newRanger(nil), &pwned.Info{Version: "v6", Algorithm: "SHA1", Built: built}}
+
+	info, err := New(Max, a, newRanger(nil), b).(pwned.InfoRanger).Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &pwned.Info{
+		Version:   "v5+v6",
+		Algorithm: "SHA1",
+		Encoding:  "union",
+		Built:     built,
+	}, info)
+
+	_, err = New(Max, newRanger(nil)).(pwned.InfoRanger).Info(context.Background())
+	assert.Equal(t, pwned.ErrNoInfo, err)
+}
@@ -0,0 +1,270 @@
+package vault
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumns lists the possible header names of each
+// column in a CSV export, in lower case.
+type csvColumns struct {
+	name, folder, username, url, password []string
+}
+
+var (
+	bitwardenColumns = csvColumns{
+		name:     []string{"name"},
+		folder:   []string{"folder"},
+		username: []string{"login_username"},
+		url:      []string{"login_uri"},
+		password: []string{"login_password"},
+	}
+	keepassColumns = csvColumns{
+		name:     []string{"title", "account"},
+		folder:   []string{"group"},
+		username: []string{"username", "user name", "login name"},
+		url:      []string{"url", "web site"},
+		password: []string{"password"},
+	}
+	onePasswordColumns = csvColumns{
+		name:     []string{"title"},
+		folder:   []string{"vault"},
+		username: []string{"username"},
+		url:      []string{"url", "website", "login url"},
+		password: []string{"password"},
+	}
+)
+
+// ReadBitwardenCSV parses a Bitwarden CSV export.
+func ReadBitwardenCSV(r io.Reader) ([]Item, error) {
+	return readCSV(r, "Bitwarden", &bitwardenColumns)
+}
+
+// ReadKeePassCSV parses a CSV export from KeePassXC or
+// KeePass 2.
+func ReadKeePassCSV(r io.Reader) ([]Item, error) {
+	return readCSV(r, "KeePass", &keepassColumns)
+}
+
+// Read1PasswordCSV parses a 1Password CSV export.
+func Read1PasswordCSV(r io.Reader) ([]Item, error) {
+	return readCSV(r, "1Password", &onePasswordColumns)
+}
+
+// readCSV parses a CSV export with a header row. Errors
+// never include the contents of the export.
+func readCSV(r io.Reader, format string, cols *csvColumns) ([]Item, error) {
+	// Some exports begin with a UTF-8 byte order mark.
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); string(bom) == "\ufeff" {
+		br.Discard(len(bom))
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, csvError(format, err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				return i
+			}
+		}
+
+		return -1
+	}
+
+	name, folder, username, url := find(cols.name), find(cols.folder), find(cols.username), find(cols.url)
+	password := find(cols.password)
+	if password < 0 {
+		return nil, fmt.Errorf("pwned/vault: %s CSV export has no password column", format)
+	}
+
+	var items []Item
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return items, nil
+		} else if err != nil {
+			return nil, csvError(format, err)
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+
+			return record[i]
+		}
+
+		items = append(items, NewItem(field(name), field(folder),
+			field(username), field(url), field(password)))
+	}
+}
+
+func csvError(format string, err error) error {
+	if pe, ok := err.(*csv.ParseError); ok {
+		return fmt.Errorf("pwned/vault: invalid %s CSV export on line %d", format, pe.Line)
+	}
+
+	return fmt.Errorf("pwned/vault: failed to read %s CSV export: %v", format, err)
+}
+
+// ReadBitwardenJSON parses an unencrypted Bitwarden JSON
+// export. Only login items are returned.
+func ReadBitwardenJSON(r io.Reader) ([]Item, error) {
+	var export struct {
+		Encrypted bool `json:"encrypted"`
+		Folders   []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"folders"`
+		Items []struct {
+			FolderID string `json:"folderId"`
+			Name     string `json:"name"`
+			Login    *struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+				URIs     []struct {
+					URI string `json:"uri"`
+				} `json:"uris"`
+			} `json:"login"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, jsonError("Bitwarden", err)
+	}
+
+	if export.Encrypted {
+		return nil, errors.New("pwned/vault: encrypted Bitwarden exports are not supported")
+	}
+
+	folders := make(map[string]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	var items []Item
+	for _, it := range export.Items {
+		if it.Login == nil {
+			continue
+		}
+
+		var url string
+		if len(it.Login.URIs) > 0 {
+			url = it.Login.URIs[0].URI
+		}
+
+		items = append(items, NewItem(it.Name, folders[it.FolderID],
+			it.Login.Username, url, it.Login.Password))
+	}
+
+	return items, nil
+}
+
+// Read1PasswordJSON parses the export.data file of a
+// 1Password 1PUX export. Use Read1PUX to read the export
+// itself.
+func Read1PasswordJSON(r io.Reader) ([]Item, error) {
+	var export struct {
+		Accounts []struct {
+			Vaults []struct {
+				Attrs struct {
+					Name string `json:"name"`
+				} `json:"attrs"`
+				Items []struct {
+					Overview struct {
+						Title string `json:"title"`
+						URL   string `json:"url"`
+					} `json:"overview"`
+					Details struct {
+						LoginFields []struct {
+							Value       string `json:"value"`
+							Designation string `json:"designation"`
+						} `json:"loginFields"`
+						Password string `json:"password"`
+					} `json:"details"`
+				} `json:"items"`
+			} `json:"vaults"`
+		} `json:"accounts"`
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, jsonError("1Password", err)
+	}
+
+	var items []Item
+	for _, account := range export.Accounts {
+		for _, v := range account.Vaults {
+			for _, it := range v.Items {
+				// Password items store their password in
+				// details, logins in the login fields.
+				username, password := "", it.Details.Password
+				for _, f := range it.Details.LoginFields {
+					switch f.Designation {
+					case "username":
+						username = f.Value
+					case "password":
+						password = f.Value
+					}
+				}
+
+				items = append(items, NewItem(it.Overview.Title, v.Attrs.Name,
+					username, it.Overview.URL, password))
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// Read1PUX parses a 1Password 1PUX export, which is a zip
+// archive containing an export.data file.
+func Read1PUX(r io.ReaderAt, size int64) ([]Item, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/vault: invalid 1PUX export: %v", err)
+	}
+
+	for _, f := range zr.File {
+		if f.Name != "export.data" {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("pwned/vault: invalid 1PUX export: %v", err)
+		}
+		defer rc.Close()
+
+		return Read1PasswordJSON(rc)
+	}
+
+	return nil, errors.New("pwned/vault: 1PUX export has no export.data file")
+}
+
+func jsonError(format string, err error) error {
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Errorf("pwned/vault: invalid %s JSON export at offset %d", format, err.Offset)
+	case *json.UnmarshalTypeError:
+		return fmt.Errorf("pwned/vault: invalid %s JSON export at offset %d", format, err.Offset)
+	default:
+		return fmt.Errorf("pwned/vault: failed to read %s JSON export: %v", format, err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package vault checks the items of a password manager
+// export against a pwned password list.
+//
+// Passwords are only ever checked with k-anonymity range
+// queries, never with full hash lookups, and are omitted
+// from reports and from the formatted Item.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go.tmthrgd.dev/pwned"
+	pwnedgrpc "go.tmthrgd.dev/pwned/grpc"
+	"google.golang.org/grpc"
+)
+
+// Item is a single login from a password manager export.
+type Item struct {
+	// Name is the item's title.
+	Name string
+	// Folder is the folder, group or vault the item is in,
+	// if any.
+	Folder string
+	// Username and URL are the item's login details, if
+	// any.
+	Username string
+	URL      string
+
+	password string
+}
+
+// NewItem returns an Item with the given password.
+func NewItem(name, folder, username, url, password string) Item {
+	return Item{name, folder, username, url, password}
+}
+
+// String returns a description of the item that omits the
+// password, so that items can be logged safely.
+func (i Item) String() string {
+	return fmt.Sprintf("item %q in %q for %q", i.Name, i.Folder, i.Username)
+}
+
+// GoString is like String. It prevents the password being
+// printed with the %#v verb.
+func (i Item) GoString() string {
+	return i.String()
+}
+
+// SearchFunc returns the number of times password occurs
+// in a pwned password list. It must not reveal the
+// password, or its full hash, to a remote server.
+type SearchFunc func(ctx context.Context, password string) (count int, err error)
+
+// ClientSearch returns a SearchFunc that calls c.Search.
+// opts are passed to each call.
+func ClientSearch(c *pwnedgrpc.Client, opts ...grpc.CallOption) SearchFunc {
+	return func(ctx context.Context, password string) (int, error) {
+		return c.Search(ctx, password, opts...)
+	}
+}
+
+// RangerSearch returns a SearchFunc that calls pwned.Search
+// with r.
+func RangerSearch(r pwned.Ranger) SearchFunc {
+	return func(ctx context.Context, password string) (int, error) {
+		return pwned.Search(ctx, r, password)
+	}
+}
+
+// Result is an item whose password has been pwned.
+type Result struct {
+	Name     string `json:"name"`
+	Folder   string `json:"folder,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+	// Count is the number of times the password has been
+	// seen, as returned by the SearchFunc.
+	Count int        `json:"count"`
+	Risk  pwned.Risk `json:"risk"`
+}
+
+// Report is the result of a Check. Pwned holds the items
+// whose password has been pwned, in the order of the
+// export.
+type Report struct {
+	// Items is the number of items with a password that
+	// were checked.
+	Items int      `json:"items"`
+	Pwned []Result `json:"pwned"`
+}
+
+// Check searches for the password of each item and returns
+// a Report of those that have been pwned. Items without a
+// password are skipped and passwords shared by several
+// items are only searched for once.
+func Check(ctx context.Context, search SearchFunc, items []Item) (*Report, error) {
+	rep := new(Report)
+
+	counts := make(map[string]int)
+	for _, item := range items {
+		if item.password == "" {
+			continue
+		}
+
+		rep.Items++
+
+		count, ok := counts[item.password]
+		if !ok {
+			var err error
+			if count, err = search(ctx, item.password); err != nil {
+				return nil, err
+			}
+
+			counts[item.password] = count
+		}
+
+		if count == 0 {
+			continue
+		}
+
+		rep.Pwned = append(rep.Pwned, Result{
+			Name:     item.Name,
+			Folder:   item.Folder,
+			Username: item.Username,
+			URL:      item.URL,
+			Count:    count,
+			Risk:     pwned.RiskOf(count),
+		})
+	}
+
+	return rep, nil
+}
+
+// WriteJSON writes the report to w as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	pwned := r.Pwned
+	if pwned == nil {
+		pwned = []Result{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(&Report{r.Items, pwned})
+}
+
+// WriteText writes the report to w as a human readable
+// list, with one line per pwned item.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, res := range r.Pwned {
+		name := res.Name
+		if res.Folder != "" {
+			name = res.Folder + "/" + name
+		}
+		if res.Username != "" {
+			name += " (" + res.Username + ")"
+		}
+
+		times := "at least once"
+		if res.Count > 1 {
+			times = fmt.Sprintf("at least %d times", res.Count)
+		}
+
+		if _, err := fmt.Fprintf(w, "%s: pwned, seen %s\n", name, times); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d of %d items have pwned passwords\n", len(r.Pwned), r.Items)
+	return err
+}
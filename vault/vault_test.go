@@ -0,0 +1,179 @@
+package vault
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/wordlist"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	items := []Item{
+		NewItem("Email", "Personal", "alice@example.com", "https://mail.example.com", "password"),
+		NewItem("Bank", "", "alice", "", "correct horse battery staple"),
+		NewItem("Card", "", "", "", ""),
+		NewItem("Forum", "", "alice", "", "password"),
+	}
+
+	var searches []string
+	search := RangerSearch(wordlist.New("password", "password", "123456"))
+
+	rep, err := Check(context.Background(), func(ctx context.Context, password string) (int, error) {
+		searches = append(searches, password)
+		return search(ctx, password)
+	}, items)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"password", "correct horse battery staple"}, searches)
+	assert.Equal(t, &Report{
+		Items: 3,
+		Pwned: []Result{
+			{"Email", "Personal", "alice@example.com", "https://mail.example.com", 2, pwned.RiskLow},
+			{"Forum", "", "alice", "", 2, pwned.RiskLow},
+		},
+	}, rep)
+
+	var buf bytes.Buffer
+	require.NoError(t, rep.WriteText(&buf))
+	assert.Equal(t, "Personal/Email (alice@example.com): pwned, seen at least 2 times\n"+
+		"Forum (alice): pwned, seen at least 2 times\n"+
+		"2 of 3 items have pwned passwords\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, rep.WriteJSON(&buf))
+	assert.NotContains(t, buf.String(), "password\"")
+
+	assert.NotContains(t, fmt.Sprint(items[0]), "password")
+	assert.NotContains(t, fmt.Sprintf("%#v", items[0]), "password")
+	assert.NotContains(t, fmt.Sprintf("%+v", items[0]), "password")
+}
+
+func TestCheckNone(t *testing.T) {
+	t.Parallel()
+
+	rep, err := Check(context.Background(), RangerSearch(wordlist.New("123456")), nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, rep.WriteJSON(&buf))
+	assert.JSONEq(t, `{"items":0,"pwned":[]}`, buf.String())
+}
+
+func TestReadCSV(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		read   func(r *strings.Reader) ([]Item, error)
+		export string
+	}{
+		{
+			"Bitwarden",
+			func(r *strings.Reader) ([]Item, error) { return ReadBitwardenCSV(r) },
+			"folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+				"Personal,,login,Email,,,0,https://mail.example.com,alice,hunter2,\n",
+		},
+		{
+			"KeePassXC",
+			func(r *strings.Reader) ([]Item, error) { return ReadKeePassCSV(r) },
+			"\ufeff\"Group\",\"Title\",\"Username\",\"Password\",\"URL\",\"Notes\"\n" +
+				"\"Personal\",\"Email\",\"alice\",\"hunter2\",\"https://mail.example.com\",\"\"\n",
+		},
+		{
+			"KeePass 2",
+			func(r *strings.Reader) ([]Item, error) { return ReadKeePassCSV(r) },
+			"\"Account\",\"Login Name\",\"Password\",\"Web Site\",\"Comments\"\n" +
+				"\"Email\",\"alice\",\"hunter2\",\"https://mail.example.com\",\"\"\n",
+		},
+		{
+			"1Password",
+			func(r *strings.Reader) ([]Item, error) { return Read1PasswordCSV(r) },
+			"Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+				"Email,https://mail.example.com,alice,hunter2,,false,false,,\n",
+		},
+	} {
+		items, err := tc.read(strings.NewReader(tc.export))
+		require.NoError(t, err, tc.name)
+		require.Len(t, items, 1, tc.name)
+
+		assert.Equal(t, "Email", items[0].Name, tc.name)
+		assert.Equal(t, "alice", items[0].Username, tc.name)
+		assert.Equal(t, "https://mail.example.com", items[0].URL, tc.name)
+		assert.Equal(t, "hunter2", items[0].password, tc.name)
+	}
+
+	_, err := ReadKeePassCSV(strings.NewReader("Title,Username\nEmail,alice\n"))
+	assert.EqualError(t, err, "pwned/vault: KeePass CSV export has no password column")
+
+	_, err = ReadBitwardenCSV(strings.NewReader("name,login_password\nEmail,\"hunter2\n"))
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "hunter2")
+}
+
+func TestReadBitwardenJSON(t *testing.T) {
+	t.Parallel()
+
+	items, err := ReadBitwardenJSON(strings.NewReader(`{
+		"encrypted": false,
+		"folders": [{"id": "f1", "name": "Personal"}],
+		"items": [
+			{"type": 1, "folderId": "f1", "name": "Email", "login": {
+				"uris": [{"match": null, "uri": "https://mail.example.com"}],
+				"username": "alice", "password": "hunter2"}},
+			{"type": 2, "folderId": null, "name": "Note", "secureNote": {"type": 0}}
+		]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Item{NewItem("Email", "Personal", "alice", "https://mail.example.com", "hunter2")}, items)
+
+	_, err = ReadBitwardenJSON(strings.NewReader(`{"encrypted": true, "items": []}`))
+	assert.EqualError(t, err, "pwned/vault: encrypted Bitwarden exports are not supported")
+}
+
+const onePasswordData = `{
+	"accounts": [{
+		"vaults": [{
+			"attrs": {"name": "Private"},
+			"items": [
+				{"overview": {"title": "Email", "url": "https://mail.example.com"},
+				 "details": {"loginFields": [
+					{"value": "alice", "designation": "username"},
+					{"value": "hunter2", "designation": "password"}]}},
+				{"overview": {"title": "Router"}, "details": {"password": "admin"}}
+			]
+		}]
+	}]
+}`
+
+func TestRead1Password(t *testing.T) {
+	t.Parallel()
+
+	want := []Item{
+		NewItem("Email", "Private", "alice", "https://mail.example.com", "hunter2"),
+		NewItem("Router", "Private", "", "", "admin"),
+	}
+
+	items, err := Read1PasswordJSON(strings.NewReader(onePasswordData))
+	require.NoError(t, err)
+	assert.Equal(t, want, items)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("export.data")
+	require.NoError(t, err)
+	w.Write([]byte(onePasswordData))
+	require.NoError(t, zw.Close())
+
+	items, err = Read1PUX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, want, items)
+}
@@ -0,0 +1,285 @@
+// Package wordlist provides a pwned.Ranger for plain text
+// lists of banned passwords.
+//
+// Each line of a wordlist is a single password. Line
+// endings are removed, but no other whitespace is, and
+// empty lines are ignored. A password that appears more
+// than once is given a count equal to the number of times
+// it appears.
+package wordlist
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/internal/filewatch"
+	"go.tmthrgd.dev/pwned/normalize"
+)
+
+// DefaultWatchInterval is how often Load checks the
+// wordlist for changes by default.
+const DefaultWatchInterval = 5 * time.Second
+
+// Ranger is a pwned.Ranger over a list of passwords. It
+// also implements pwnedgrpc.Lookup.
+//
+// A Ranger is safe for concurrent use.
+type Ranger struct {
+	path string
+
+	watch    time.Duration
+	watcher  *filewatch.Watcher
+	reloaded func(passwords int, err error)
+
+	normalize bool
+	invalid   normalize.InvalidUTF8
+
+	version string
+
+	mu    sync.RWMutex
+	sets  map[string][]byte
+	n     int
+	built time.Time
+}
+
+// New returns a Ranger over the given passwords. Use
+// normalize.Expand to add the normalised form of each
+// password.
+func New(passwords ...string) *Ranger {
+	r := new(Ranger)
+	r.set(build(passwords))
+	return r
+}
+
+// Load returns a Ranger over the wordlist at path. The
+// wordlist is reloaded in the background whenever it
+// changes, until Close is called.
+func Load(path string, opts ...Option) (*Ranger, error) {
+	r := &Ranger{
+		path:  path,
+		watch: DefaultWatchInterval,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.watch > 0 {
+		r.watcher = filewatch.Watch(path, r.watch, func() { r.Reload() })
+	}
+
+	passwords, err := r.read()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	r.set(build(passwords))
+	return r, nil
+}
+
+// Close stops watching the wordlist for changes. It does
+// nothing for a Ranger returned by New.
+func (r *Ranger) Close() error {
+	if r.watcher != nil {
+		r.watcher.Stop()
+	}
+
+	return nil
+}
+
+// Parse reads a wordlist from rd.
+func Parse(rd io.Reader) ([]string, error) {
+	var passwords []string
+
+	s := bufio.NewScanner(rd)
+	for s.Scan() {
+		if password := s.Text(); password != "" {
+			passwords = append(passwords, password)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("pwned/wordlist: failed to read wordlist: %v", err)
+	}
+
+	return passwords, nil
+}
+
+func (r *Ranger) read() ([]string, error) {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/wordlist: failed to open wordlist: %v", err)
+	}
+	defer f.Close()
+
+	passwords, err := Parse(f)
+	if err != nil || !r.normalize {
+		return passwords, err
+	}
+
+	passwords, err = normalize.Expand(passwords, r.invalid)
+	if err != nil {
+		return nil, fmt.Errorf("pwned/wordlist: failed to normalise wordlist: %v", err)
+	}
+
+	return passwords, nil
+}
+
+func build(passwords []string) (map[string][]byte, int) {
+	count := make(map[string]uint64, len(passwords))
+	for _, password := range passwords {
+		count[password]++
+	}
+
+	type result struct {
+		suffix [pwned.SuffixSize]byte
+		count  uint64
+	}
+
+	results := make(map[string][]result)
+	for password, n := range count {
+		prefix, suffix := pwned.SplitDigest(sha1.Sum([]byte(password)))
+		results[prefix] = append(results[prefix], result{suffix, n})
+	}
+
+	// Each set is sorted by suffix, as a range query to
+	// the Pwned Passwords API would be.
+	sets := make(map[string][]byte, len(results))
+	for prefix, rs := range results {
+		sort.Slice(rs, func(i, j int) bool {
+			return string(rs[i].suffix[:]) < string(rs[j].suffix[:])
+		})
+
+		set := make([]byte, 0, pwned.Size(len(rs)))
+		for _, res := range rs {
+			set = pwned.AppendResult(set, res.suffix, res.count)
+		}
+
+		sets[prefix] = set
+	}
+
+	return sets, len(count)
+}
+
+func (r *Ranger) set(sets map[string][]byte, n int) {
+	r.mu.Lock()
+	r.sets, r.n, r.built = sets, n, time.Now()
+	r.mu.Unlock()
+}
+
+// Reload reads the wordlist again. If the wordlist cannot
+// be read, the existing passwords continue to be used. It
+// does nothing for a Ranger returned by New.
+func (r *Ranger) Reload() error {
+	if r.path == "" {
+		return nil
+	}
+
+	passwords, err := r.read()
+
+	var n int
+	if err == nil {
+		var sets map[string][]byte
+		sets, n = build(passwords)
+		r.set(sets, n)
+	}
+
+	if r.reloaded != nil {
+		r.reloaded(n, err)
+	}
+
+	return err
+}
+
+func (r *Ranger) get(prefix string) []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sets[strings.ToLower(prefix)]
+}
+
+// Len returns the number of distinct passwords.
+func (r *Ranger) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.n
+}
+
+// Info implements pwned.InfoRanger. Built is the time the
+// wordlist was last loaded.
+func (r *Ranger) Info(ctx context.Context) (*pwned.Info, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &pwned.Info{
+		Version:   r.version,
+		Entries:   uint64(r.n),
+		Prefixes:  uint64(len(r.sets)),
+		Algorithm: "SHA1",
+		Encoding:  "wordlist",
+		Built:     r.built,
+	}, nil
+}
+
+// Range implements pwned.Ranger.
+func (r *Ranger) Range(ctx context.Context, prefix string) ([]byte, error) {
+	return append([]byte(nil), r.get(prefix)...), nil
+}
+
+// Lookup implements pwnedgrpc.Lookup.
+func (r *Ranger) Lookup(ctx context.Context, digest [sha1.Size]byte) (count int, err error) {
+	prefix, suffix := pwned.SplitDigest(digest)
+	return pwned.SearchSet(r.get(prefix), suffix), nil
+}
+
+// Option allows the behaviour of the Ranger to be
+// configured.
+type Option func(*Ranger)
+
+// WithWatch sets how often the wordlist is checked for
+// changes. The check is performed in the background. An
+// interval of zero disables reloading. It defaults to
+// DefaultWatchInterval.
+func WithWatch(interval time.Duration) Option {
+	return func(r *Ranger) {
+		r.watch = interval
+	}
+}
+
+// WithReloadHook sets a function that is called whenever
+// the wordlist is reloaded, with the number of distinct
+// passwords loaded or the error that prevented them from
+// loading.
+func WithReloadHook(fn func(passwords int, err error)) Option {
+	return func(r *Ranger) {
+		r.reloaded = fn
+	}
+}
+
+// WithNormalization adds the NFKC form of each password to
+// the wordlist, where it differs, so that clients that
+// normalise passwords before hashing them find it. invalid
+// chooses what happens to lines that are not valid UTF-8,
+// see normalize.String.
+func WithNormalization(invalid normalize.InvalidUTF8) Option {
+	return func(r *Ranger) {
+		r.normalize, r.invalid = true, invalid
+	}
+}
+
+// WithVersion records the version of the wordlist, as
+// reported by Info.
+func WithVersion(version string) Option {
+	return func(r *Ranger) {
+		r.version = version
+	}
+}
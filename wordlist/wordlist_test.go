@@ -0,0 +1,143 @@
+package wordlist
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.tmthrgd.dev/pwned"
+	"go.tmthrgd.dev/pwned/normalize"
+)
+
+func search(t *testing.T, r *Ranger, password string) int {
+	digest := sha1.Sum([]byte(password))
+	prefix, suffix := pwned.SplitDigest(digest)
+
+	set, err := r.Range(context.Background(), prefix)
+	require.NoError(t, err)
+
+	count, err := r.Lookup(context.Background(), digest)
+	require.NoError(t, err)
+	assert.Equal(t, pwned.SearchSet(set, suffix), count)
+
+	return count
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	r := New("password", "password", "Acme2019!")
+	assert.Equal(t, 2, r.Len())
+	assert.Equal(t, 2, search(t, r, "password"))
+	assert.Equal(t, 1, search(t, r, "Acme2019!"))
+	assert.Equal(t, 0, search(t, r, "acme2019!"))
+}
+
+func TestSorted(t *testing.T) {
+	t.Parallel()
+
+	passwords := make([]string, 10000)
+	for i := range passwords {
+		passwords[i] = strconv.Itoa(i)
+	}
+
+	sets, _ := build(passwords)
+
+	var multiple int
+	for prefix, set := range sets {
+		if len(set) > pwned.Size(1) {
+			multiple++
+		}
+
+		for ; len(set) > pwned.Size(1); set = set[pwned.SuffixSize+1:] {
+			next := set[pwned.SuffixSize+1:]
+			assert.True(t, bytes.Compare(set[:pwned.SuffixSize], next[:pwned.SuffixSize]) < 0,
+				"set for %s is not sorted", prefix)
+		}
+	}
+
+	assert.NotZero(t, multiple)
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-wordlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "banned.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("Acme2019!\r\n\r\nSummer 2019\n"), 0644))
+
+	reloaded := make(chan int, 1)
+	r, err := Load(path, WithWatch(time.Millisecond), WithVersion("2019-06"),
+		WithReloadHook(func(passwords int, err error) {
+			assert.NoError(t, err)
+			reloaded <- passwords
+		}))
+	require.NoError(t, err)
+	defer r.Close()
+
+	info, err := r.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "2019-06", info.Version)
+
+	assert.Equal(t, 2, r.Len())
+	assert.Equal(t, 1, search(t, r, "Acme2019!"))
+	assert.Equal(t, 1, search(t, r, "Summer 2019"))
+	assert.Equal(t, 0, search(t, r, "Winter2019"))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("Winter2019\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	select {
+	case n := <-reloaded:
+		assert.Equal(t, 1, n)
+	case <-time.After(10 * time.Second):
+		t.Fatal("wordlist was not reloaded")
+	}
+
+	assert.Equal(t, 0, search(t, r, "Acme2019!"))
+	assert.Equal(t, 1, search(t, r, "Winter2019"))
+	assert.Equal(t, 1, r.Len())
+
+	_, err = Load(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestLoadNormalization(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pwned-wordlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "banned.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("ｐａｓｓ１２３\npassword\n"), 0644))
+
+	r, err := Load(path, WithWatch(0), WithNormalization(normalize.Reject))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, r.Len())
+	assert.Equal(t, 1, search(t, r, "ｐａｓｓ１２３"))
+	assert.Equal(t, 1, search(t, r, "pass123"))
+	assert.Equal(t, 1, search(t, r, "password"))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("pass\xff\n"), 0644))
+
+	_, err = Load(path, WithWatch(0), WithNormalization(normalize.Reject))
+	assert.Error(t, err)
+
+	r, err = Load(path, WithWatch(0), WithNormalization(normalize.Replace))
+	require.NoError(t, err)
+	assert.Equal(t, 1, search(t, r, "pass\ufffd"))
+}